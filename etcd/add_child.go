@@ -1,8 +1,15 @@
 package etcd
 
+import "context"
+
 // Add a new directory with a random etcd-generated key under the given path.
 func (c *Client) AddChildDir(key string, ttl uint64) (*Response, error) {
-	raw, err := c.post(key, "", ttl)
+	return c.AddChildDirContext(context.Background(), key, ttl)
+}
+
+// AddChildDirContext is like AddChildDir but gives up once ctx is done.
+func (c *Client) AddChildDirContext(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	raw, err := c.post(ctx, key, "", ttl)
 
 	if err != nil {
 		return nil, err
@@ -13,7 +20,12 @@ func (c *Client) AddChildDir(key string, ttl uint64) (*Response, error) {
 
 // Add a new file with a random etcd-generated key under the given path.
 func (c *Client) AddChild(key string, value string, ttl uint64) (*Response, error) {
-	raw, err := c.post(key, value, ttl)
+	return c.AddChildContext(context.Background(), key, value, ttl)
+}
+
+// AddChildContext is like AddChild but gives up once ctx is done.
+func (c *Client) AddChildContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	raw, err := c.post(ctx, key, value, ttl)
 
 	if err != nil {
 		return nil, err
@@ -3,7 +3,7 @@ package etcd
 import "testing"
 
 func TestAddChild(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("fooDir", true)
 		c.Delete("nonexistentDir", true)
@@ -38,7 +38,7 @@ func TestAddChild(t *testing.T) {
 }
 
 func TestAddChildDir(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("fooDir", true)
 		c.Delete("nonexistentDir", true)
@@ -0,0 +1,373 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// The roles etcd always has. Users with the root role may do anything,
+// including managing auth, while the permissions of the guest role apply
+// to requests without credentials.
+const (
+	RootRole  = "root"
+	GuestRole = "guest"
+)
+
+// User is a user of the auth API.
+type User struct {
+	User     string   `json:"user"`
+	Password string   `json:"password,omitempty"`
+	Roles    []string `json:"roles"`
+}
+
+// UnmarshalJSON accepts the roles of a user either as names or, as sent
+// by etcd 2.3 and later, as whole roles.
+func (u *User) UnmarshalJSON(data []byte) error {
+	d := struct {
+		User     string            `json:"user"`
+		Password string            `json:"password"`
+		Roles    []json.RawMessage `json:"roles"`
+	}{}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+
+	u.User, u.Password, u.Roles = d.User, d.Password, nil
+	for _, raw := range d.Roles {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			var role Role
+			if err := json.Unmarshal(raw, &role); err != nil {
+				return err
+			}
+			name = role.Role
+		}
+		u.Roles = append(u.Roles, name)
+	}
+	return nil
+}
+
+// Role is a role of the auth API, granting permissions on keys.
+type Role struct {
+	Role        string      `json:"role"`
+	Permissions Permissions `json:"permissions"`
+}
+
+// Permissions are the permissions granted by a role.
+type Permissions struct {
+	KV RWPermission `json:"kv"`
+}
+
+// RWPermission lists the keys that may be read, and those that may be
+// written. A key ending with "*" stands for all keys it prefixes.
+type RWPermission struct {
+	Read  []string `json:"read"`
+	Write []string `json:"write"`
+}
+
+// PermissionType is the kind of access a permission allows.
+type PermissionType int
+
+const (
+	ReadPermission PermissionType = iota
+	WritePermission
+	ReadWritePermission
+)
+
+// rwPermission returns the permission of the given type on keys.
+func (t PermissionType) rwPermission(keys []string) RWPermission {
+	var p RWPermission
+	if t == ReadPermission || t == ReadWritePermission {
+		p.Read = keys
+	}
+	if t == WritePermission || t == ReadWritePermission {
+		p.Write = keys
+	}
+	return p
+}
+
+// The bodies of the requests changing users and roles.
+type (
+	userUpdate struct {
+		User     string   `json:"user"`
+		Password string   `json:"password,omitempty"`
+		Grant    []string `json:"grant,omitempty"`
+		Revoke   []string `json:"revoke,omitempty"`
+	}
+	roleUpdate struct {
+		Role   string       `json:"role"`
+		Grant  *Permissions `json:"grant,omitempty"`
+		Revoke *Permissions `json:"revoke,omitempty"`
+	}
+)
+
+// AuthEnabled reports whether the cluster requires credentials.
+func (c *Client) AuthEnabled() (bool, error) {
+	return c.AuthEnabledContext(context.Background())
+}
+
+// AuthEnabledContext is like AuthEnabled but gives up once ctx is done.
+func (c *Client) AuthEnabledContext(ctx context.Context) (bool, error) {
+	var status struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := c.sendAPI(ctx, NewRawRequest("GET", "auth/enable", nil, nil), &status, http.StatusOK); err != nil {
+		return false, err
+	}
+	return status.Enabled, nil
+}
+
+// EnableAuth makes the cluster check credentials. The root user must
+// have been added first.
+//
+// Like all changes to auth once it is enabled, disabling it again needs
+// the credentials of a user with the root role; see SetCredentials.
+func (c *Client) EnableAuth() error {
+	return c.EnableAuthContext(context.Background())
+}
+
+// EnableAuthContext is like EnableAuth but gives up once ctx is done.
+func (c *Client) EnableAuthContext(ctx context.Context) error {
+	return c.sendAPI(ctx, NewRawRequest("PUT", "auth/enable", nil, nil), nil, http.StatusOK)
+}
+
+// DisableAuth stops the cluster from checking credentials.
+func (c *Client) DisableAuth() error {
+	return c.DisableAuthContext(context.Background())
+}
+
+// DisableAuthContext is like DisableAuth but gives up once ctx is done.
+func (c *Client) DisableAuthContext(ctx context.Context) error {
+	return c.sendAPI(ctx, NewRawRequest("DELETE", "auth/enable", nil, nil), nil, http.StatusOK)
+}
+
+// Users returns the names of all users.
+func (c *Client) Users() ([]string, error) {
+	return c.UsersContext(context.Background())
+}
+
+// UsersContext is like Users but gives up once ctx is done.
+func (c *Client) UsersContext(ctx context.Context) ([]string, error) {
+	var list struct {
+		Users []json.RawMessage `json:"users"`
+	}
+	if err := c.sendAPI(ctx, NewRawRequest("GET", "auth/users", nil, nil), &list, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	// etcd 2.3 and later send whole users rather than names
+	names := make([]string, 0, len(list.Users))
+	for _, raw := range list.Users {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			var u User
+			if err := json.Unmarshal(raw, &u); err != nil {
+				return nil, err
+			}
+			name = u.User
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// User returns the user with the given name, without its password.
+func (c *Client) User(name string) (*User, error) {
+	return c.UserContext(context.Background(), name)
+}
+
+// UserContext is like User but gives up once ctx is done.
+func (c *Client) UserContext(ctx context.Context, name string) (*User, error) {
+	u := new(User)
+	if err := c.sendAPI(ctx, NewRawRequest("GET", userPath(name), nil, nil), u, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// AddUser adds a user with the given password. Since etcd adds and
+// changes users alike, AddUser first checks that the user does not exist,
+// and fails with an *HTTPError satisfying IsConflict if it does rather
+// than changing its password. Use ChangePassword for that.
+func (c *Client) AddUser(name, password string) error {
+	return c.AddUserContext(context.Background(), name, password)
+}
+
+// AddUserContext is like AddUser but gives up once ctx is done.
+func (c *Client) AddUserContext(ctx context.Context, name, password string) error {
+	if err := c.checkAbsent(ctx, userPath(name), "User "+name); err != nil {
+		return err
+	}
+	return c.updateUser(ctx, userUpdate{User: name, Password: password})
+}
+
+// ChangePassword changes the password of a user.
+func (c *Client) ChangePassword(name, password string) error {
+	return c.ChangePasswordContext(context.Background(), name, password)
+}
+
+// ChangePasswordContext is like ChangePassword but gives up once ctx is
+// done.
+func (c *Client) ChangePasswordContext(ctx context.Context, name, password string) error {
+	return c.updateUser(ctx, userUpdate{User: name, Password: password})
+}
+
+// GrantUser gives roles to a user.
+func (c *Client) GrantUser(name string, roles ...string) error {
+	return c.GrantUserContext(context.Background(), name, roles...)
+}
+
+// GrantUserContext is like GrantUser but gives up once ctx is done.
+func (c *Client) GrantUserContext(ctx context.Context, name string, roles ...string) error {
+	return c.updateUser(ctx, userUpdate{User: name, Grant: roles})
+}
+
+// RevokeUser takes roles away from a user.
+func (c *Client) RevokeUser(name string, roles ...string) error {
+	return c.RevokeUserContext(context.Background(), name, roles...)
+}
+
+// RevokeUserContext is like RevokeUser but gives up once ctx is done.
+func (c *Client) RevokeUserContext(ctx context.Context, name string, roles ...string) error {
+	return c.updateUser(ctx, userUpdate{User: name, Revoke: roles})
+}
+
+func (c *Client) updateUser(ctx context.Context, update userUpdate) error {
+	rr, err := newJSONRequest("PUT", userPath(update.User), update)
+	if err != nil {
+		return err
+	}
+	return c.sendAPI(ctx, rr, nil, http.StatusOK, http.StatusCreated)
+}
+
+// RemoveUser removes a user.
+func (c *Client) RemoveUser(name string) error {
+	return c.RemoveUserContext(context.Background(), name)
+}
+
+// RemoveUserContext is like RemoveUser but gives up once ctx is done.
+func (c *Client) RemoveUserContext(ctx context.Context, name string) error {
+	return c.sendAPI(ctx, NewRawRequest("DELETE", userPath(name), nil, nil), nil, http.StatusOK)
+}
+
+// Roles returns the names of all roles.
+func (c *Client) Roles() ([]string, error) {
+	return c.RolesContext(context.Background())
+}
+
+// RolesContext is like Roles but gives up once ctx is done.
+func (c *Client) RolesContext(ctx context.Context) ([]string, error) {
+	var list struct {
+		Roles []json.RawMessage `json:"roles"`
+	}
+	if err := c.sendAPI(ctx, NewRawRequest("GET", "auth/roles", nil, nil), &list, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	// etcd 2.3 and later send whole roles rather than names
+	names := make([]string, 0, len(list.Roles))
+	for _, raw := range list.Roles {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			var r Role
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return nil, err
+			}
+			name = r.Role
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Role returns the role with the given name.
+func (c *Client) Role(name string) (*Role, error) {
+	return c.RoleContext(context.Background(), name)
+}
+
+// RoleContext is like Role but gives up once ctx is done.
+func (c *Client) RoleContext(ctx context.Context, name string) (*Role, error) {
+	r := new(Role)
+	if err := c.sendAPI(ctx, NewRawRequest("GET", rolePath(name), nil, nil), r, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddRole adds a role granting no permissions. Like AddUser, it fails
+// with an *HTTPError satisfying IsConflict if the role already exists.
+func (c *Client) AddRole(name string) error {
+	return c.AddRoleContext(context.Background(), name)
+}
+
+// AddRoleContext is like AddRole but gives up once ctx is done.
+func (c *Client) AddRoleContext(ctx context.Context, name string) error {
+	if err := c.checkAbsent(ctx, rolePath(name), "Role "+name); err != nil {
+		return err
+	}
+	return c.updateRole(ctx, roleUpdate{Role: name})
+}
+
+// GrantRole makes a role grant access of the given type to keys. A key
+// ending with "*", such as "/config/*", stands for all keys it prefixes.
+func (c *Client) GrantRole(name string, keys []string, perm PermissionType) error {
+	return c.GrantRoleContext(context.Background(), name, keys, perm)
+}
+
+// GrantRoleContext is like GrantRole but gives up once ctx is done.
+func (c *Client) GrantRoleContext(ctx context.Context, name string, keys []string, perm PermissionType) error {
+	return c.updateRole(ctx, roleUpdate{Role: name, Grant: &Permissions{KV: perm.rwPermission(keys)}})
+}
+
+// RevokeRole stops a role from granting access of the given type to keys.
+func (c *Client) RevokeRole(name string, keys []string, perm PermissionType) error {
+	return c.RevokeRoleContext(context.Background(), name, keys, perm)
+}
+
+// RevokeRoleContext is like RevokeRole but gives up once ctx is done.
+func (c *Client) RevokeRoleContext(ctx context.Context, name string, keys []string, perm PermissionType) error {
+	return c.updateRole(ctx, roleUpdate{Role: name, Revoke: &Permissions{KV: perm.rwPermission(keys)}})
+}
+
+func (c *Client) updateRole(ctx context.Context, update roleUpdate) error {
+	rr, err := newJSONRequest("PUT", rolePath(update.Role), update)
+	if err != nil {
+		return err
+	}
+	return c.sendAPI(ctx, rr, nil, http.StatusOK, http.StatusCreated)
+}
+
+// checkAbsent fails with a conflict if the user or role at p, described
+// by what, exists. It cannot stop another client from adding it right
+// after.
+func (c *Client) checkAbsent(ctx context.Context, p, what string) error {
+	err := c.sendAPI(ctx, NewRawRequest("GET", p, nil, nil), nil, http.StatusOK)
+	switch {
+	case err == nil:
+		return &HTTPError{StatusCode: http.StatusConflict, Message: "auth: " + what + " already exists."}
+	case errors.Is(err, &HTTPError{StatusCode: http.StatusNotFound}):
+		return nil
+	}
+	return err
+}
+
+// RemoveRole removes a role, taking it away from all users.
+func (c *Client) RemoveRole(name string) error {
+	return c.RemoveRoleContext(context.Background(), name)
+}
+
+// RemoveRoleContext is like RemoveRole but gives up once ctx is done.
+func (c *Client) RemoveRoleContext(ctx context.Context, name string) error {
+	return c.sendAPI(ctx, NewRawRequest("DELETE", rolePath(name), nil, nil), nil, http.StatusOK)
+}
+
+func userPath(name string) string {
+	return "auth/users/" + url.PathEscape(name)
+}
+
+func rolePath(name string) string {
+	return "auth/roles/" + url.PathEscape(name)
+}
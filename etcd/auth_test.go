@@ -0,0 +1,135 @@
+package etcd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestUserUnmarshal(t *testing.T) {
+	tests := []string{
+		`{"user":"alice","roles":["reader","writer"]}`,
+		`{"user":"alice","roles":[{"role":"reader","permissions":{"kv":{"read":["/*"]}}},{"role":"writer"}]}`,
+	}
+	for i, body := range tests {
+		var u User
+		if err := json.Unmarshal([]byte(body), &u); err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		want := User{User: "alice", Roles: []string{"reader", "writer"}}
+		if !reflect.DeepEqual(u, want) {
+			t.Errorf("#%d: user = %+v, want %+v", i, u, want)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	root := NewClient(cluster.URLs())
+	if err := root.EnableAuth(); err == nil {
+		t.Fatal("auth enabled without a root user")
+	}
+	if err := root.AddUser("root", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddRole("config"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddRole("config"); !IsConflict(err) {
+		t.Fatalf("AddRole = %v, want a conflict", err)
+	}
+	if err := root.GrantRole("config", []string{"/config/*"}, ReadWritePermission); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddUser("app", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddUser("app", "other"); !IsConflict(err) {
+		t.Fatalf("AddUser = %v, want a conflict", err)
+	}
+	if err := root.GrantUser("app", "config"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.RevokeRole(GuestRole, []string{"/*"}, WritePermission); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := root.EnableAuth(); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, err := root.AuthEnabled(); err != nil || !enabled {
+		t.Fatalf("AuthEnabled = %v, %v, want true", enabled, err)
+	}
+
+	// managing auth now needs the root user
+	if _, err := root.Users(); !IsUnauthorized(err) {
+		t.Fatalf("Users = %v, want unauthorized", err)
+	}
+	root.SetCredentials("root", "secret")
+	users, err := root.Users()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, []string{"app", "root"}) {
+		t.Fatalf("users = %v", users)
+	}
+	u, err := root.User("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u.Roles, []string{"config"}) {
+		t.Fatalf("roles of app = %v", u.Roles)
+	}
+	r, err := root.Role("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (RWPermission{Read: []string{"/config/*"}, Write: []string{"/config/*"}}); !reflect.DeepEqual(r.Permissions.KV, want) {
+		t.Fatalf("permissions = %+v, want %+v", r.Permissions.KV, want)
+	}
+
+	// keys are only writable as permitted
+	app := NewClient(cluster.URLs())
+	app.SetCredentials("app", "pass")
+	if _, err := app.Set("/config/a", "1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Set("/other", "1", 0); !IsUnauthorized(err) {
+		t.Fatalf("Set = %v, want unauthorized", err)
+	}
+	guest := NewClient(cluster.URLs())
+	if _, err := guest.Get("/config/a", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := guest.Set("/config/a", "2", 0); !IsUnauthorized(err) {
+		t.Fatalf("Set = %v, want unauthorized", err)
+	}
+
+	if err := root.RevokeUser("app", "config"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Set("/config/a", "2", 0); !IsUnauthorized(err) {
+		t.Fatalf("Set = %v, want unauthorized once revoked", err)
+	}
+
+	if err := root.RemoveUser("app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.RemoveRole("config"); err != nil {
+		t.Fatal(err)
+	}
+	if roles, _ := root.Roles(); !reflect.DeepEqual(roles, []string{GuestRole, RootRole}) {
+		t.Fatalf("roles = %v", roles)
+	}
+	if err := root.DisableAuth(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := guest.Set("/other", "1", 0); err != nil {
+		t.Fatal(err)
+	}
+}
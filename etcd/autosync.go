@@ -0,0 +1,105 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// MembershipChange describes how the machines of the cluster changed
+// between two syncs.
+type MembershipChange struct {
+	// Old and New are the machines before and after the change, sorted.
+	Old, New []string
+	// Added and Removed are the machines that joined and left, sorted.
+	Added, Removed []string
+}
+
+// AutoSync calls SyncClusterContext every interval until ctx is done, so
+// that the client follows members as they are added and removed. A sync
+// finding no machines leaves the machine list as it is, and each new
+// machine list is written to the persistence writer, if any; see
+// SetPersistence.
+//
+// Changes to the machine list are sent on the returned channel, which is
+// closed once ctx is done. Changes are merged while the channel is not
+// read, so that a slow reader never holds syncs up.
+func (c *Client) AutoSync(ctx context.Context, interval time.Duration) <-chan MembershipChange {
+	changes := make(chan MembershipChange)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := sortedMachines(c.cluster.machines())
+		var pending *MembershipChange
+		for {
+			if !c.SyncClusterContext(ctx) && ctx.Err() == nil {
+				logger.Warning("autosync: cannot reach any machine of ", last)
+			}
+			if machines := sortedMachines(c.cluster.machines()); !equalStrings(machines, last) {
+				if pending == nil {
+					pending = &MembershipChange{Old: last}
+				}
+				pending.New = machines
+				pending.Added = subtractStrings(pending.New, pending.Old)
+				pending.Removed = subtractStrings(pending.Old, pending.New)
+				if equalStrings(pending.New, pending.Old) {
+					// the machines changed back before anyone was told
+					pending = nil
+				}
+				last = machines
+			}
+
+			for wait := true; wait; {
+				var out chan<- MembershipChange
+				var change MembershipChange
+				if pending != nil {
+					out, change = changes, *pending
+				}
+
+				select {
+				case out <- change:
+					pending = nil
+				case <-ticker.C:
+					wait = false
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
+func sortedMachines(machines []string) []string {
+	sorted := append([]string(nil), machines...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// subtractStrings returns the strings of a missing from b.
+func subtractStrings(a, b []string) []string {
+	var diff []string
+	for _, s := range a {
+		if !containsString(b, s) {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,142 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+// lastWrite keeps the last thing written to it.
+type lastWrite struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *lastWrite) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (w *lastWrite) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf
+}
+
+func nextChange(t *testing.T, changes <-chan MembershipChange) MembershipChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no membership change")
+	}
+	panic("unreachable")
+}
+
+func TestAutoSync(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	first := cluster.Members[0].URL
+	c := NewClient([]string{first})
+	saved := new(lastWrite)
+	c.SetPersistence(saved)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := c.AutoSync(ctx, 10*time.Millisecond)
+
+	all := cluster.URLs()
+	sort.Strings(all)
+	change := nextChange(t, changes)
+	if !reflect.DeepEqual(change.Old, []string{first}) || !reflect.DeepEqual(change.New, all) || len(change.Added) != 2 || change.Removed != nil {
+		t.Fatalf("change = %+v, want the other members added", change)
+	}
+	var config struct {
+		Cluster struct {
+			Machines []string `json:"machines"`
+		} `json:"cluster"`
+	}
+	if err := json.Unmarshal(saved.Bytes(), &config); err != nil {
+		t.Fatal(err)
+	}
+	if machines := sortedMachines(config.Cluster.Machines); !reflect.DeepEqual(machines, all) {
+		t.Fatalf("persisted machines = %v, want %v", machines, all)
+	}
+
+	removed := cluster.Members[2]
+	if err := c.RemoveMember(removed.ID); err != nil {
+		t.Fatal(err)
+	}
+	change = nextChange(t, changes)
+	if !reflect.DeepEqual(change.Removed, []string{removed.URL}) || change.Added != nil {
+		t.Fatalf("change = %+v, want %s removed", change, removed.URL)
+	}
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestAutoSyncNeverEmpties(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	for _, m := range cluster.Members {
+		if err := c.RemoveMember(m.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for change := range c.AutoSync(ctx, 10*time.Millisecond) {
+		t.Fatalf("change = %+v, want none", change)
+	}
+	if machines := c.GetCluster(); len(machines) != 2 {
+		t.Fatalf("machines = %v, want both kept", machines)
+	}
+}
+
+func TestAutoSyncStopsMidSync(t *testing.T) {
+	// a member that accepts connections but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := NewClient([]string{"http://" + ln.Addr().String()})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range c.AutoSync(ctx, time.Minute) {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AutoSync still running after ctx was done")
+	}
+}
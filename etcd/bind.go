@@ -0,0 +1,437 @@
+package etcd
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Causes of a DecodeError when binding directory trees.
+var (
+	errNotDir      = errors.New("node is not a directory")
+	errUnsupported = errors.New("unsupported type")
+)
+
+// UnmarshalNode loads the tree under node, as returned by a recursive Get,
+// into v, which must be a pointer. Fields and entries with no matching
+// key are left as they are.
+//
+// Directory trees map onto Go values as follows:
+//
+//   - a struct is a directory with a key per exported field, named after
+//     the field or its `etcd:"name"` tag; fields tagged `etcd:"-"` are
+//     left out
+//   - a map with string keys is a directory with a key per entry
+//   - a slice or an array is a directory with keys "0", "1" and so on
+//   - any other value is the value of a key: strings as they are, numbers
+//     and booleans as formatted by strconv, and types implementing
+//     encoding.TextMarshaler and encoding.TextUnmarshaler as text
+//
+// Pointers stand for the value they point to, and nil pointers, maps and
+// slices for no key at all.
+func UnmarshalNode(node *Node, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal into non-pointer %T", v)
+	}
+	return unmarshalNode(node, rv.Elem())
+}
+
+// LoadDir gets the tree under dir and loads it into v, which must be a
+// pointer; see UnmarshalNode.
+func (c *Client) LoadDir(dir string, v interface{}) (*Response, error) {
+	return c.LoadDirContext(context.Background(), dir, v)
+}
+
+// LoadDirContext is like LoadDir but gives up once ctx is done.
+func (c *Client) LoadDirContext(ctx context.Context, dir string, v interface{}) (*Response, error) {
+	resp, err := c.GetContext(ctx, dir, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return resp, UnmarshalNode(resp.Node, v)
+}
+
+// StoreDir writes v as the tree under dir, mapped as described for
+// UnmarshalNode, and returns the keys it set or deleted in the order it
+// did so. Keys already holding the right value are not set again, and keys
+// missing from v are left as they are, except for the items of slices and
+// arrays past their length, which are deleted so that a shorter slice
+// loads back as stored.
+//
+// Directories are created with SetDir if v has nothing to store in them,
+// values are written with Set, and extra items deleted last, so that a
+// failure leaves the keys changed so far in place.
+func (c *Client) StoreDir(dir string, v interface{}) ([]string, error) {
+	return c.StoreDirContext(context.Background(), dir, v)
+}
+
+// StoreDirContext is like StoreDir but gives up once ctx is done.
+func (c *Client) StoreDirContext(ctx context.Context, dir string, v interface{}) ([]string, error) {
+	root := path.Clean("/" + dir)
+	want := newFlatTree()
+	if err := want.addValue(root, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	if !want.dirs[root] {
+		return nil, fmt.Errorf("cannot store %T as a directory", v)
+	}
+
+	have := newFlatTree()
+	resp, err := c.GetContext(ctx, root, false, true)
+	switch {
+	case err == nil:
+		have.addNode(resp.Node)
+	case !IsKeyNotFound(err):
+		return nil, err
+	}
+
+	var changed []string
+	dirs := make([]string, 0, len(want.dirs))
+	for key := range want.dirs {
+		dirs = append(dirs, key)
+	}
+	sort.Strings(dirs)
+	for _, key := range dirs {
+		if have.dirs[key] || want.hasChildren(key) {
+			// Set creates the directories it needs
+			continue
+		}
+		if _, err := c.SetDirContext(ctx, key, 0); err != nil {
+			return changed, err
+		}
+		changed = append(changed, key)
+	}
+	keys := make([]string, 0, len(want.values))
+	for key := range want.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := want.values[key]
+		if old, ok := have.values[key]; ok && old == value {
+			continue
+		}
+		if _, err := c.SetContext(ctx, key, value, 0); err != nil {
+			return changed, err
+		}
+		changed = append(changed, key)
+	}
+	for _, key := range have.extraItems(want.lists) {
+		if _, err := c.DeleteContext(ctx, key, true); err != nil {
+			return changed, err
+		}
+		changed = append(changed, key)
+	}
+	return changed, nil
+}
+
+func unmarshalNode(node *Node, rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalNode(node, rv.Elem())
+	}
+	if rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		if node.Dir {
+			return &DecodeError{Key: node.Key, Err: errDirValue}
+		}
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(node.Value)); err != nil {
+			return &DecodeError{Key: node.Key, Err: err}
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() != reflect.Uint8 {
+			if !node.Dir {
+				return &DecodeError{Key: node.Key, Err: errNotDir}
+			}
+			return unmarshalDir(node, rv)
+		}
+	}
+
+	if node.Dir {
+		return &DecodeError{Key: node.Key, Err: errDirValue}
+	}
+	if err := parseValue(node.Value, rv); err != nil {
+		return &DecodeError{Key: node.Key, Err: err}
+	}
+	return nil
+}
+
+func unmarshalDir(node *Node, rv reflect.Value) error {
+	children := make(map[string]*Node, len(node.Nodes))
+	for _, child := range node.Nodes {
+		children[path.Base(child.Key)] = child
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := fieldKey(t.Field(i))
+			if !ok {
+				continue
+			}
+			if child := children[name]; child != nil {
+				if err := unmarshalNode(child, rv.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return &DecodeError{Key: node.Key, Err: errUnsupported}
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for name, child := range children {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := unmarshalNode(child, elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), elem)
+		}
+	case reflect.Slice, reflect.Array:
+		items := itemNodes(node.Nodes)
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), len(items), len(items)))
+		}
+		for i, item := range items {
+			if i == rv.Len() {
+				break
+			}
+			if err := unmarshalNode(item, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// itemNodes returns the items of a directory standing for a slice: those
+// named after an index first, ordered by it, then the others by creation.
+func itemNodes(nodes Nodes) Nodes {
+	items := append(Nodes(nil), nodes...)
+	sort.SliceStable(items, func(i, j int) bool {
+		a, errA := strconv.Atoi(path.Base(items[i].Key))
+		b, errB := strconv.Atoi(path.Base(items[j].Key))
+		switch {
+		case errA == nil && errB == nil:
+			return a < b
+		case errA == nil || errB == nil:
+			return errA == nil
+		}
+		return items[i].CreatedIndex < items[j].CreatedIndex
+	})
+	return items
+}
+
+// fieldKey returns the key a struct field is stored as, and false if it is
+// not stored.
+func fieldKey(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	name := f.Name
+	if tag := f.Tag.Get("etcd"); tag != "" {
+		if tag == "-" {
+			return "", false
+		}
+		name = strings.Split(tag, ",")[0]
+	}
+	return name, true
+}
+
+func parseValue(s string, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Slice:
+		rv.SetBytes([]byte(s))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return errUnsupported
+	}
+	return nil
+}
+
+// flatTree is a directory tree as the set of its directories and the
+// values of its keys, by full key. The directories holding the items of a
+// slice or an array are also kept with their length.
+type flatTree struct {
+	dirs   map[string]bool
+	values map[string]string
+	lists  map[string]int
+}
+
+func newFlatTree() *flatTree {
+	return &flatTree{
+		dirs:   make(map[string]bool),
+		values: make(map[string]string),
+		lists:  make(map[string]int),
+	}
+}
+
+func (t *flatTree) addNode(node *Node) {
+	if !node.Dir {
+		t.values[node.Key] = node.Value
+		return
+	}
+	t.dirs[node.Key] = true
+	for _, child := range node.Nodes {
+		t.addNode(child)
+	}
+}
+
+func (t *flatTree) addValue(key string, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type().Implements(textMarshalerType) {
+		text, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		t.values[key] = string(text)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		t.dirs[key] = true
+		for i := 0; i < rv.NumField(); i++ {
+			name, ok := fieldKey(rv.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if err := t.addValue(path.Join(key, name), rv.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot store %s at %s: map keys must be strings", rv.Type(), key)
+		}
+		if rv.IsNil() {
+			return nil
+		}
+		t.dirs[key] = true
+		for _, k := range rv.MapKeys() {
+			if err := t.addValue(path.Join(key, k.String()), rv.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			t.values[key] = string(rv.Bytes())
+			return nil
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		t.dirs[key] = true
+		t.lists[key] = rv.Len()
+		for i := 0; i < rv.Len(); i++ {
+			if err := t.addValue(path.Join(key, strconv.Itoa(i)), rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		t.values[key] = rv.String()
+	case reflect.Bool:
+		t.values[key] = strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		t.values[key] = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		t.values[key] = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		t.values[key] = strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits())
+	default:
+		return fmt.Errorf("cannot store %s at %s", rv.Type(), key)
+	}
+	return nil
+}
+
+// extraItems returns the keys of the tree that are items of the given
+// lists at or past their length, sorted.
+func (t *flatTree) extraItems(lists map[string]int) []string {
+	var extra []string
+	add := func(key string) {
+		n, ok := lists[path.Dir(key)]
+		if !ok {
+			return
+		}
+		if i, err := strconv.Atoi(path.Base(key)); err == nil && i >= n {
+			extra = append(extra, key)
+		}
+	}
+	for key := range t.dirs {
+		add(key)
+	}
+	for key := range t.values {
+		add(key)
+	}
+	sort.Strings(extra)
+	return extra
+}
+
+// hasChildren reports whether anything is stored under the directory dir.
+func (t *flatTree) hasChildren(dir string) bool {
+	prefix := dir + "/"
+	if dir == "/" {
+		prefix = dir
+	}
+	for key := range t.values {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for key := range t.dirs {
+		if key != dir && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package etcd
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+type bindBackend struct {
+	Addr   string `etcd:"addr"`
+	Weight int    `etcd:"weight"`
+}
+
+type bindConfig struct {
+	Name     string                `etcd:"name"`
+	Debug    bool                  `etcd:"debug"`
+	Timeout  time.Duration         `etcd:"timeout"`
+	Ratio    float64               `etcd:"ratio"`
+	Started  time.Time             `etcd:"started"`
+	Backends []bindBackend         `etcd:"backends"`
+	Labels   map[string]string     `etcd:"labels"`
+	Limits   *struct{ Max uint16 } `etcd:"limits"`
+	Ignored  string                `etcd:"-"`
+	Empty    map[string]string     `etcd:"empty"`
+	secret   string
+}
+
+func TestStoreAndLoadDir(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	cfg := bindConfig{
+		Name:     "web",
+		Debug:    true,
+		Timeout:  3 * time.Second,
+		Ratio:    0.5,
+		Started:  time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC),
+		Backends: []bindBackend{{"10.0.0.1:80", 1}, {"10.0.0.2:80", 2}},
+		Labels:   map[string]string{"tier": "front"},
+		Limits:   &struct{ Max uint16 }{100},
+		Ignored:  "not stored",
+		Empty:    map[string]string{},
+		secret:   "not stored either",
+	}
+
+	changed, err := c.StoreDir("/config", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/config/empty",
+		"/config/backends/0/addr", "/config/backends/0/weight",
+		"/config/backends/1/addr", "/config/backends/1/weight",
+		"/config/debug", "/config/labels/tier", "/config/limits/Max",
+		"/config/name", "/config/ratio", "/config/started", "/config/timeout",
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	if resp, _ := c.Get("/config/timeout", false, false); resp.Node.Value != "3000000000" {
+		t.Fatalf("timeout = %q", resp.Node.Value)
+	}
+
+	var got bindConfig
+	if _, err := c.LoadDir("/config", &got); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Ignored, cfg.secret = "", ""
+	if !reflect.DeepEqual(got, cfg) {
+		t.Fatalf("loaded %+v, want %+v", got, cfg)
+	}
+
+	// only what changed is written again
+	cfg.Backends[1].Weight = 5
+	cfg.Labels["zone"] = "a"
+	if changed, err = c.StoreDir("/config", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"/config/backends/1/weight", "/config/labels/zone"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+}
+
+func TestStoreDirShrinksSlices(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	type list struct {
+		L []string
+		B []bindBackend
+	}
+	if _, err := c.StoreDir("/s", list{L: []string{"a", "b", "c"}, B: []bindBackend{{"x", 1}, {"y", 2}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// items past the new length are deleted
+	changed, err := c.StoreDir("/s", list{L: []string{"x"}, B: []bindBackend{{"x", 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/s/L/0", "/s/B/1", "/s/L/1", "/s/L/2"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+
+	var got list
+	if _, err := c.LoadDir("/s", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.L, []string{"x"}) || !reflect.DeepEqual(got.B, []bindBackend{{"x", 1}}) {
+		t.Fatalf("loaded %+v, want the shorter slices", got)
+	}
+}
+
+func TestLoadDirMixedSlice(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("/m/L/b", "first named", 0)
+	c.Set("/m/L/10", "ten", 0)
+	c.Set("/m/L/a", "second named", 0)
+	c.Set("/m/L/2", "two", 0)
+
+	// indexes first, by index, then the other names by creation
+	want := []string{"two", "ten", "first named", "second named"}
+	var got struct{ L []string }
+	if _, err := c.LoadDir("/m", &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.L, want) {
+		t.Fatalf("loaded %q, want %q", got.L, want)
+	}
+
+	// whatever order the nodes come in
+	resp, err := c.Get("/m/L", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := resp.Node.Nodes
+	for i := 0; i < 20; i++ {
+		rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+		var values []string
+		for _, node := range itemNodes(nodes) {
+			values = append(values, node.Value)
+		}
+		if !reflect.DeepEqual(values, want) {
+			t.Fatalf("items of %v = %q, want %q", nodes, values, want)
+		}
+	}
+}
+
+func TestLoadDirErrors(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("/config/weight", "heavy", 0)
+	c.Set("/config/addr/nested", "x", 0)
+
+	var b bindBackend
+	_, err := c.LoadDir("/config", &b)
+	decodeErr, ok := err.(*DecodeError)
+	if !ok || (decodeErr.Key != "/config/weight" && decodeErr.Key != "/config/addr") {
+		t.Fatalf("LoadDir = %v, want a decode error", err)
+	}
+
+	if _, err := c.LoadDir("/config", b); err == nil {
+		t.Fatal("LoadDir into a non-pointer succeeded")
+	}
+	if _, err := c.StoreDir("/other", "just a string"); err == nil {
+		t.Fatal("StoreDir of a string succeeded")
+	}
+}
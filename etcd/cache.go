@@ -0,0 +1,382 @@
+package etcd
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CacheHandlers are notified of the changes applied to a Cache. Each of
+// them may be nil. They are called one at a time from Run, in the order of
+// the changes, with copies of the nodes that they are free to keep.
+type CacheHandlers struct {
+	// OnAdd is called with a node that was created.
+	OnAdd func(node *Node)
+	// OnUpdate is called with the previous and the new version of a node
+	// that was changed.
+	OnUpdate func(prev, node *Node)
+	// OnDelete is called with a node that was deleted or expired. A deleted
+	// directory is passed along with its children, for which OnDelete is
+	// not called, whether the deletion was watched or found by a resync.
+	OnDelete func(prev *Node)
+}
+
+// Cache is a copy of the tree of nodes under a key, kept in sync by
+// watching it.
+//
+// The tree is loaded with a recursive Get, and then updated with the
+// changes reported by a Watcher, in the order of their ModifiedIndex. When
+// the Watcher has to resync, the whole tree is replaced and the handlers
+// are notified of the differences.
+type Cache struct {
+	client   *Client
+	prefix   string
+	handlers CacheHandlers
+
+	mu    sync.RWMutex
+	root  *Node
+	index uint64
+
+	synced     chan struct{}
+	syncedOnce sync.Once
+}
+
+// NewCache returns a cache of the tree under prefix. It stays empty until
+// Run is called.
+func NewCache(client *Client, prefix string, handlers CacheHandlers) *Cache {
+	return &Cache{
+		client:   client,
+		prefix:   path.Join("/", prefix),
+		handlers: handlers,
+		synced:   make(chan struct{}),
+	}
+}
+
+// Run loads the tree and keeps it in sync until ctx is done, or until
+// watching it fails for good. It returns ctx.Err() in the former case.
+// Run must only be called once.
+func (c *Cache) Run(ctx context.Context) error {
+	resp, err := c.client.GetContext(ctx, c.prefix, true, true)
+	if IsKeyNotFound(err) {
+		resp, err = &Response{EtcdIndex: errorIndex(err)}, nil
+	}
+	if err != nil {
+		return err
+	}
+	c.notify(c.replace(resp.Node, resp.EtcdIndex))
+	c.syncedOnce.Do(func() { close(c.synced) })
+
+	w := c.client.NewWatcher(c.prefix, resp.EtcdIndex+1, true)
+	for {
+		resp, err := w.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		if Action(resp.Action) == ActionResync {
+			c.notify(c.replace(resp.Node, resp.EtcdIndex))
+		} else {
+			c.notify(c.apply(resp))
+		}
+	}
+}
+
+// Synced returns a channel that is closed once the tree has been loaded.
+func (c *Cache) Synced() <-chan struct{} {
+	return c.synced
+}
+
+// Get returns a copy of the node at key, including its children, or nil
+// if there is none.
+func (c *Cache) Get(key string) *Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	parent, i := c.find(key)
+	if parent == nil {
+		if c.isRoot(key) {
+			return copyNode(c.root)
+		}
+		return nil
+	}
+	if i < 0 {
+		return nil
+	}
+	return copyNode(parent.Nodes[i])
+}
+
+// Snapshot returns a copy of the whole tree, or nil if the prefix does not
+// exist, along with the etcd index it is up to date with.
+func (c *Cache) Snapshot() (*Node, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return copyNode(c.root), c.index
+}
+
+// Index returns the etcd index the cache is up to date with.
+func (c *Cache) Index() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.index
+}
+
+// cacheChange is a change to be passed to the handlers.
+type cacheChange struct {
+	prev, node *Node
+}
+
+// apply applies the change reported by resp, unless it is older than the
+// tree.
+func (c *Cache) apply(resp *Response) []cacheChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp.Node.ModifiedIndex <= c.index {
+		return nil
+	}
+	c.index = resp.Node.ModifiedIndex
+
+	if Action(resp.Action).removesNode() {
+		if prev := c.remove(resp.Node.Key); prev != nil {
+			return []cacheChange{{prev: prev}}
+		}
+		return nil
+	}
+
+	node := *resp.Node
+	node.Nodes = nil
+	return c.insert(&node)
+}
+
+// replace replaces the whole tree, and returns the differences between the
+// old one and the new one.
+func (c *Cache) replace(root *Node, index uint64) []cacheChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if root != nil {
+		root.Key = c.prefix
+		sortNodes(root)
+	}
+	old, cur := flattenNodes(c.root), flattenNodes(root)
+	c.root, c.index = root, index
+
+	var changes []cacheChange
+	for _, key := range sortedKeys(cur) {
+		prev, ok := old[key]
+		if !ok {
+			changes = append(changes, cacheChange{node: copyNode(cur[key])})
+		} else if prev.ModifiedIndex != cur[key].ModifiedIndex {
+			changes = append(changes, cacheChange{prev: copyNode(prev), node: copyNode(cur[key])})
+		}
+	}
+	for _, key := range sortedKeys(old) {
+		if _, ok := cur[key]; ok {
+			continue
+		}
+		// the children of a deleted directory go along with it
+		if parent := path.Dir(key); key != c.prefix && old[parent] != nil && cur[parent] == nil {
+			continue
+		}
+		changes = append(changes, cacheChange{prev: old[key]})
+	}
+	return changes
+}
+
+func (c *Cache) notify(changes []cacheChange) {
+	h := c.handlers
+	for _, change := range changes {
+		switch {
+		case change.prev == nil:
+			if h.OnAdd != nil {
+				h.OnAdd(change.node)
+			}
+		case change.node == nil:
+			if h.OnDelete != nil {
+				h.OnDelete(change.prev)
+			}
+		default:
+			if h.OnUpdate != nil {
+				h.OnUpdate(change.prev, change.node)
+			}
+		}
+	}
+}
+
+func (c *Cache) isRoot(key string) bool {
+	return path.Join("/", key) == c.prefix
+}
+
+// relative returns the names leading from the prefix to key, or false if
+// key is not under the prefix.
+func (c *Cache) relative(key string) ([]string, bool) {
+	key = path.Join("/", key)
+	rel := strings.TrimPrefix(key, c.prefix)
+	if c.prefix != "/" && (rel == key || !strings.HasPrefix(rel, "/")) {
+		return nil, false
+	}
+	return strings.Split(strings.Trim(rel, "/"), "/"), true
+}
+
+// find returns the parent of the node at key and the node's position among
+// its children, or -1 if it does not exist. The parent is nil if key is
+// the prefix itself, or if it does not exist.
+func (c *Cache) find(key string) (*Node, int) {
+	names, ok := c.relative(key)
+	if !ok || c.isRoot(key) || c.root == nil {
+		return nil, -1
+	}
+
+	parent := c.root
+	for i, name := range names {
+		j := searchNodes(parent.Nodes, path.Join(parent.Key, name))
+		if i == len(names)-1 {
+			return parent, j
+		}
+		if j < 0 || !parent.Nodes[j].Dir {
+			return nil, -1
+		}
+		parent = parent.Nodes[j]
+	}
+	return nil, -1
+}
+
+// insert puts node in the tree, creating its missing parents, and returns
+// the resulting changes.
+func (c *Cache) insert(node *Node) []cacheChange {
+	if c.isRoot(node.Key) {
+		prev := c.root
+		if prev != nil && prev.Dir && node.Dir {
+			node.Nodes = prev.Nodes
+		}
+		c.root = node
+		return []cacheChange{{prev: copyNode(prev), node: copyNode(node)}}
+	}
+
+	names, ok := c.relative(node.Key)
+	if !ok {
+		return nil
+	}
+
+	var changes []cacheChange
+	if c.root == nil {
+		c.root = &Node{Key: c.prefix, Dir: true}
+		changes = append(changes, cacheChange{node: copyNode(c.root)})
+	}
+
+	parent := c.root
+	for _, name := range names[:len(names)-1] {
+		key := path.Join(parent.Key, name)
+		j := searchNodes(parent.Nodes, key)
+		if j < 0 {
+			dir := &Node{Key: key, Dir: true, ModifiedIndex: node.ModifiedIndex, CreatedIndex: node.ModifiedIndex}
+			parent.Nodes = insertNode(parent.Nodes, dir)
+			changes = append(changes, cacheChange{node: copyNode(dir)})
+			j = searchNodes(parent.Nodes, key)
+		}
+		parent = parent.Nodes[j]
+	}
+
+	j := searchNodes(parent.Nodes, node.Key)
+	if j < 0 {
+		parent.Nodes = insertNode(parent.Nodes, node)
+		return append(changes, cacheChange{node: copyNode(node)})
+	}
+
+	prev := parent.Nodes[j]
+	if prev.Dir && node.Dir {
+		node.Nodes = prev.Nodes
+	}
+	parent.Nodes[j] = node
+	return append(changes, cacheChange{prev: copyNode(prev), node: copyNode(node)})
+}
+
+// remove removes the node at key from the tree and returns it, or nil if
+// there was none.
+func (c *Cache) remove(key string) *Node {
+	if c.isRoot(key) {
+		prev := c.root
+		c.root = nil
+		return prev
+	}
+
+	parent, i := c.find(key)
+	if parent == nil || i < 0 {
+		return nil
+	}
+	prev := parent.Nodes[i]
+	parent.Nodes = append(parent.Nodes[:i:i], parent.Nodes[i+1:]...)
+	return prev
+}
+
+// searchNodes returns the position of the node with the given key among
+// nodes sorted by key, or -1.
+func searchNodes(nodes Nodes, key string) int {
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i].Key >= key })
+	if i < len(nodes) && nodes[i].Key == key {
+		return i
+	}
+	return -1
+}
+
+// insertNode inserts node among nodes sorted by key.
+func insertNode(nodes Nodes, node *Node) Nodes {
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i].Key >= node.Key })
+	nodes = append(nodes, nil)
+	copy(nodes[i+1:], nodes[i:])
+	nodes[i] = node
+	return nodes
+}
+
+// sortNodes sorts the children of node by key, recursively.
+func sortNodes(node *Node) {
+	sort.Sort(node.Nodes)
+	for _, child := range node.Nodes {
+		sortNodes(child)
+	}
+}
+
+// flattenNodes maps the keys of node and all of its descendants to the
+// nodes.
+func flattenNodes(node *Node) map[string]*Node {
+	nodes := make(map[string]*Node)
+	var walk func(*Node)
+	walk = func(n *Node) {
+		nodes[n.Key] = n
+		for _, child := range n.Nodes {
+			walk(child)
+		}
+	}
+	if node != nil {
+		walk(node)
+	}
+	return nodes
+}
+
+func sortedKeys(nodes map[string]*Node) []string {
+	keys := make([]string, 0, len(nodes))
+	for key := range nodes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyNode returns a deep copy of node.
+func copyNode(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	n := *node
+	if node.Nodes != nil {
+		n.Nodes = make(Nodes, len(node.Nodes))
+		for i, child := range node.Nodes {
+			n.Nodes[i] = copyNode(child)
+		}
+	}
+	return &n
+}
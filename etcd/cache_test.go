@@ -0,0 +1,193 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// changeRecorder records the changes reported to CacheHandlers.
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *changeRecorder) handlers() CacheHandlers {
+	return CacheHandlers{
+		OnAdd:    func(node *Node) { r.record("add " + node.Key) },
+		OnUpdate: func(prev, node *Node) { r.record("update " + node.Key + " " + prev.Value + " " + node.Value) },
+		OnDelete: func(prev *Node) { r.record("delete " + prev.Key) },
+	}
+}
+
+func (r *changeRecorder) record(change string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+func (r *changeRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changes := r.changes
+	r.changes = nil
+	return changes
+}
+
+func TestCache(t *testing.T) {
+	c := newTestClient(t)
+	c.Set("/conf/a", "1", 0)
+	c.Set("/conf/sub/b", "2", 0)
+	c.Set("/other", "x", 0)
+
+	var r changeRecorder
+	cache := NewCache(c, "/conf", r.handlers())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cache.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run = %v, want %v", err, context.Canceled)
+		}
+	}()
+
+	select {
+	case <-cache.Synced():
+	case <-time.After(5 * time.Second):
+		t.Fatal("cache was not synced")
+	}
+	if node := cache.Get("/conf/sub/b"); node == nil || node.Value != "2" {
+		t.Fatalf("Get = %+v, want a value of 2", node)
+	}
+	if node := cache.Get("/other"); node != nil {
+		t.Fatalf("Get = %+v, want nil outside of the prefix", node)
+	}
+	want := []string{"add /conf", "add /conf/a", "add /conf/sub", "add /conf/sub/b"}
+	if changes := r.take(); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %q, want %q", changes, want)
+	}
+
+	c.Set("/conf/a", "3", 0)
+	c.Set("/conf/new/c", "4", 0)
+	c.Delete("/conf/sub", true)
+	resp, _ := c.Set("/other", "y", 0)
+
+	waitForCacheIndex(t, cache, resp.EtcdIndex-1)
+	want = []string{"update /conf/a 1 3", "add /conf/new", "add /conf/new/c", "delete /conf/sub"}
+	if changes := r.take(); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %q, want %q", changes, want)
+	}
+
+	root, index := cache.Snapshot()
+	if index != resp.EtcdIndex-1 {
+		t.Fatalf("index = %d, want %d", index, resp.EtcdIndex-1)
+	}
+	var keys []string
+	for _, node := range root.Nodes {
+		keys = append(keys, node.Key)
+	}
+	if want := []string{"/conf/a", "/conf/new"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %q, want %q", keys, want)
+	}
+
+	// snapshots are copies
+	root.Nodes[0].Value = "changed"
+	if node := cache.Get("/conf/a"); node.Value != "3" {
+		t.Fatalf("value = %q, want 3", node.Value)
+	}
+}
+
+func TestCacheResync(t *testing.T) {
+	var r changeRecorder
+	cache := NewCache(nil, "/conf", r.handlers())
+
+	cache.notify(cache.replace(&Node{Key: "/conf", Dir: true, ModifiedIndex: 1, Nodes: Nodes{
+		{Key: "/conf/b", Value: "1", ModifiedIndex: 3},
+		{Key: "/conf/a", Value: "1", ModifiedIndex: 2},
+		{Key: "/conf/d", Dir: true, ModifiedIndex: 4, Nodes: Nodes{
+			{Key: "/conf/d/e", Value: "1", ModifiedIndex: 4},
+		}},
+	}}, 4))
+	r.take()
+
+	// changes already in the tree are ignored
+	cache.notify(cache.apply(&Response{Action: "set", Node: &Node{Key: "/conf/a", Value: "0", ModifiedIndex: 4}}))
+	if changes := r.take(); changes != nil {
+		t.Fatalf("changes = %q, want none", changes)
+	}
+
+	cache.notify(cache.replace(&Node{Key: "/conf", Dir: true, ModifiedIndex: 1, Nodes: Nodes{
+		{Key: "/conf/a", Value: "1", ModifiedIndex: 2},
+		{Key: "/conf/b", Value: "2", ModifiedIndex: 7},
+		{Key: "/conf/c", Value: "1", ModifiedIndex: 6},
+	}}, 9))
+	want := []string{"update /conf/b 1 2", "add /conf/c", "delete /conf/d"}
+	if changes := r.take(); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %q, want %q", changes, want)
+	}
+	if cache.Index() != 9 {
+		t.Fatalf("index = %d, want 9", cache.Index())
+	}
+
+	cache.notify(cache.replace(nil, 10))
+	want = []string{"delete /conf"}
+	if changes := r.take(); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %q, want %q", changes, want)
+	}
+	if root, _ := cache.Snapshot(); root != nil {
+		t.Fatalf("root = %+v, want nil", root)
+	}
+}
+
+func TestCacheDeleteDir(t *testing.T) {
+	tree := func() *Node {
+		return &Node{Key: "/conf", Dir: true, ModifiedIndex: 1, Nodes: Nodes{
+			{Key: "/conf/a", Value: "1", ModifiedIndex: 2},
+			{Key: "/conf/d", Dir: true, ModifiedIndex: 3, Nodes: Nodes{
+				{Key: "/conf/d/e", Value: "1", ModifiedIndex: 3},
+				{Key: "/conf/d/f", Dir: true, ModifiedIndex: 4, Nodes: Nodes{
+					{Key: "/conf/d/f/g", Value: "1", ModifiedIndex: 4},
+				}},
+			}},
+		}}
+	}
+
+	deleted := func(apply func(cache *Cache)) []*Node {
+		var nodes []*Node
+		cache := NewCache(nil, "/conf", CacheHandlers{OnDelete: func(prev *Node) { nodes = append(nodes, prev) }})
+		cache.notify(cache.replace(tree(), 4))
+		apply(cache)
+		return nodes
+	}
+
+	// a directory is deleted the same way whether watched or resynced
+	watched := deleted(func(cache *Cache) {
+		cache.notify(cache.apply(&Response{Action: "delete", Node: &Node{Key: "/conf/d", Dir: true, ModifiedIndex: 5}}))
+	})
+	resynced := deleted(func(cache *Cache) {
+		root := tree()
+		root.Nodes = root.Nodes[:1]
+		cache.notify(cache.replace(root, 5))
+	})
+	for _, nodes := range [][]*Node{watched, resynced} {
+		if len(nodes) != 1 || nodes[0].Key != "/conf/d" || len(nodes[0].Nodes) != 2 || len(nodes[0].Nodes[1].Nodes) != 1 {
+			t.Fatalf("deleted %+v, want /conf/d along with its children", nodes)
+		}
+	}
+}
+
+func waitForCacheIndex(t *testing.T, cache *Cache, index uint64) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if cache.Index() >= index {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("cache index = %d, want %d", cache.Index(), index)
+}
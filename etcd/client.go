@@ -1,6 +1,7 @@
 package etcd
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -14,6 +15,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -47,7 +49,12 @@ type credentials struct {
 	password string
 }
 
+// Client is safe for concurrent use by multiple goroutines, including
+// while its configuration is being changed.
 type Client struct {
+	// mu guards all unexported fields below, except cluster which
+	// has a lock of its own.
+	mu          sync.RWMutex
 	config      Config   `json:"config"`
 	cluster     *Cluster `json:"cluster"`
 	httpClient  *http.Client
@@ -55,20 +62,36 @@ type Client struct {
 	transport   *http.Transport
 	persistence io.Writer
 	cURLch      chan string
+	// closed is set by Close, after which no request is sent.
+	closed bool
+	// persistMu serializes writes to persistence.
+	persistMu sync.Mutex
+	// srv finds the machines of the cluster again on sync, if set. It
+	// never changes once the client is created.
+	srv *srvSource
 	// CheckRetry can be used to control the policy for failed requests
 	// and modify the cluster if needed.
 	// The client calls it before sending requests again, and
 	// stops retrying if CheckRetry returns some error. The cases that
 	// this function needs to handle include no response and unexpected
 	// http status code of response.
-	// If CheckRetry is nil, client will call the default one
-	// `DefaultCheckRetry`.
 	// Argument cluster is the etcd.Cluster object that these requests have been made on.
 	// Argument numReqs is the number of http.Requests that have been made so far.
 	// Argument lastResp is the http.Responses from the last request.
 	// Argument err is the reason of the failure.
+	// CheckRetry should be set before the client is shared between
+	// goroutines.
+	//
+	// Deprecated: set RetryPolicy instead. CheckRetry is only called if
+	// RetryPolicy is nil.
 	CheckRetry func(cluster *Cluster, numReqs int,
 		lastResp http.Response, err error) error
+	// RetryPolicy decides whether and when failed requests are sent again.
+	// If both RetryPolicy and CheckRetry are nil, the client uses the
+	// policy returned by NewBackoffRetryPolicy.
+	// RetryPolicy should be set before the client is shared between
+	// goroutines.
+	RetryPolicy RetryPolicy
 }
 
 // NewClient create a basic client that is configured to be used
@@ -176,19 +199,48 @@ func NewClientFromReader(reader io.Reader) (*Client, error) {
 
 // Override the Client's HTTP Transport object
 func (c *Client) SetTransport(tr *http.Transport) {
-	c.httpClient.Transport = tr
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Requests in flight keep using the old http.Client.
+	c.httpClient = &http.Client{Transport: tr}
 	c.transport = tr
 }
 
 func (c *Client) SetCredentials(username, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.credentials = &credentials{username, password}
 }
 
+// Close closes the idle connections of the client. Requests sent once it
+// is closed fail with ErrClientClosed.
 func (c *Client) Close() {
-	c.transport.DisableKeepAlives = true
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the transport may be in use, so it is not changed
+	c.closed = true
 	c.transport.CloseIdleConnections()
 }
 
+// isClosed reports whether the client was closed.
+func (c *Client) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.closed
+}
+
+// getHTTPClient returns the http.Client to send requests with.
+func (c *Client) getHTTPClient() *http.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.httpClient
+}
+
 // initHTTPClient initializes a HTTP client for etcd client
 func (c *Client) initHTTPClient() {
 	c.transport = &http.Transport{
@@ -228,6 +280,9 @@ func (c *Client) initHTTPSClient(cert, key string) error {
 // SetPersistence sets a writer to which the config will be
 // written every time it's changed.
 func (c *Client) SetPersistence(writer io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.persistence = writer
 }
 
@@ -248,17 +303,41 @@ func (c *Client) SetConsistency(consistency string) error {
 	if !(consistency == STRONG_CONSISTENCY || consistency == WEAK_CONSISTENCY) {
 		return errors.New("The argument must be either STRONG_CONSISTENCY or WEAK_CONSISTENCY.")
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.config.Consistency = consistency
 	return nil
 }
 
+// consistency returns the consistency level of the client.
+func (c *Client) consistency() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.config.Consistency
+}
+
 // Sets the DialTimeout value
 func (c *Client) SetDialTimeout(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.config.DialTimeout = d
 }
 
 // AddRootCA adds a root CA cert for the etcd client
 func (c *Client) AddRootCA(caCert string) error {
+	err := c.addRootCA(caCert)
+	c.saveConfig()
+	return err
+}
+
+func (c *Client) addRootCA(caCert string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.httpClient == nil {
 		return errors.New("Client has not been initialized yet!")
 	}
@@ -274,6 +353,10 @@ func (c *Client) AddRootCA(caCert string) error {
 		panic("AddRootCA(): Transport type assert should not fail")
 	}
 
+	// The TLS config may be in use by requests in flight, so the
+	// transport is replaced rather than modified in place.
+	tr = tr.Clone()
+
 	if tr.TLSClientConfig.RootCAs == nil {
 		caCertPool := x509.NewCertPool()
 		ok = caCertPool.AppendCertsFromPEM(certBytes)
@@ -289,44 +372,79 @@ func (c *Client) AddRootCA(caCert string) error {
 		err = errors.New("Unable to load caCert")
 	}
 
+	c.transport = tr
+	c.httpClient = &http.Client{Transport: tr}
 	c.config.CaCertFile = append(c.config.CaCertFile, caCert)
-	c.saveConfig()
 
 	return err
 }
 
 // SetCluster updates cluster information using the given machine list.
 func (c *Client) SetCluster(machines []string) bool {
-	success := c.internalSyncCluster(machines)
-	return success
+	return c.SetClusterContext(context.Background(), machines)
+}
+
+// SetClusterContext is like SetCluster but gives up once ctx is done.
+func (c *Client) SetClusterContext(ctx context.Context, machines []string) bool {
+	return c.syncCluster(ctx, machines, nil)
 }
 
 func (c *Client) GetCluster() []string {
-	return c.cluster.Machines
+	return c.cluster.machines()
 }
 
 // SyncCluster updates the cluster information using the internal machine list.
 // If no members are found, the intenral machine list is left untouched.
+//
+// Clients created by NewClientFromSRV resolve their SRV records again
+// first, and use the machines found there if none can be reached. The
+// published members keep the order the records rank them in, ahead of
+// the others.
 func (c *Client) SyncCluster() bool {
-	return c.internalSyncCluster(c.cluster.Machines)
+	return c.SyncClusterContext(context.Background())
 }
 
-// internalSyncCluster syncs cluster information using the given machine list.
-func (c *Client) internalSyncCluster(machines []string) bool {
+// SyncClusterContext is like SyncCluster but gives up once ctx is done.
+func (c *Client) SyncClusterContext(ctx context.Context) bool {
+	if c.srv == nil {
+		return c.syncCluster(ctx, c.cluster.machines(), nil)
+	}
+
+	resolved, err := c.srv.resolve(ctx)
+	if err != nil {
+		logger.Warningf("sync: cannot resolve SRV records of %s: %v", c.srv.domain, err)
+	}
+	machines := append(resolved, subtractStrings(c.cluster.machines(), resolved)...)
+	// the machines keep the order the records rank them in
+	if c.syncCluster(ctx, machines, machines) {
+		return true
+	}
+	if len(resolved) == 0 {
+		return false
+	}
+	c.cluster.update(resolved, 0)
+	c.saveConfig()
+	return true
+}
+
+// syncCluster syncs cluster information using the given machine list. The
+// members found in ranked are put first, in that order; see updateFromStr.
+func (c *Client) syncCluster(ctx context.Context, machines, ranked []string) bool {
 	// comma-separated list of machines in the cluster.
 	members := ""
 
 	for _, machine := range machines {
 		httpPath := c.createHttpPath(machine, path.Join(version, "members"))
-		resp, err := c.httpClient.Get(httpPath)
+		resp, err := c.probeGet(ctx, httpPath)
 		if err != nil {
 			// try another machine in the cluster
 			continue
 		}
 
 		if resp.StatusCode != http.StatusOK { // fall-back to old endpoint
+			resp.Body.Close()
 			httpPath := c.createHttpPath(machine, path.Join(version, "machines"))
-			resp, err := c.httpClient.Get(httpPath)
+			resp, err := c.probeGet(ctx, httpPath)
 			if err != nil {
 				// try another machine in the cluster
 				continue
@@ -354,7 +472,11 @@ func (c *Client) internalSyncCluster(machines []string) bool {
 
 			urls := make([]string, 0)
 			for _, m := range mCollection {
-				urls = append(urls, m.ClientURLs...)
+				for _, u := range m.ClientURLs {
+					if u != "" {
+						urls = append(urls, u)
+					}
+				}
 			}
 
 			members = strings.Join(urls, ",")
@@ -366,9 +488,19 @@ func (c *Client) internalSyncCluster(machines []string) bool {
 		}
 
 		// update Machines List
-		c.cluster.updateFromStr(members)
-		logger.Debug("sync.machines ", c.cluster.Machines)
+		c.cluster.updateFromStr(members, ranked)
+		logger.Debug("sync.machines ", c.cluster.machines())
 		c.saveConfig()
+
+		// the leader is kept until it leaves or fails
+		if c.cluster.leader() != "" {
+			return true
+		}
+		if leader, err := c.syncLeader(ctx); err != nil {
+			logger.Debug("sync.leader failed: ", err)
+		} else {
+			logger.Debug("sync.leader ", leader)
+		}
 		return true
 	}
 
@@ -394,8 +526,12 @@ func (c *Client) createHttpPath(serverName string, _path string) string {
 // DefaultDial attempts to open a TCP connection to the provided address, explicitly
 // enabling keep-alives with a one-second interval.
 func (c *Client) DefaultDial(network, addr string) (net.Conn, error) {
+	c.mu.RLock()
+	timeout := c.config.DialTimeout
+	c.mu.RUnlock()
+
 	dialer := net.Dialer{
-		Timeout:   c.config.DialTimeout,
+		Timeout:   timeout,
 		KeepAlive: time.Second,
 	}
 
@@ -403,35 +539,52 @@ func (c *Client) DefaultDial(network, addr string) (net.Conn, error) {
 }
 
 func (c *Client) OpenCURL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.cURLch = make(chan string, defaultBufferSize)
 }
 
 func (c *Client) CloseCURL() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.cURLch = nil
 }
 
-func (c *Client) sendCURL(command string) {
+func sendCURL(cURLch chan string, command string) {
 	go func() {
 		select {
-		case c.cURLch <- command:
+		case cURLch <- command:
 		default:
 		}
 	}()
 }
 
 func (c *Client) RecvCURL() string {
-	return <-c.cURLch
+	c.mu.RLock()
+	cURLch := c.cURLch
+	c.mu.RUnlock()
+
+	return <-cURLch
 }
 
 // saveConfig saves the current config using c.persistence.
 func (c *Client) saveConfig() error {
-	if c.persistence != nil {
+	c.persistMu.Lock()
+	defer c.persistMu.Unlock()
+
+	c.mu.RLock()
+	persistence := c.persistence
+	c.mu.RUnlock()
+
+	if persistence != nil {
 		b, err := json.Marshal(c)
 		if err != nil {
 			return err
 		}
 
-		_, err = c.persistence.Write(b)
+		_, err = persistence.Write(b)
 		if err != nil {
 			return err
 		}
@@ -443,6 +596,9 @@ func (c *Client) saveConfig() error {
 // MarshalJSON implements the Marshaller interface
 // as defined by the standard JSON package.
 func (c *Client) MarshalJSON() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	b, err := json.Marshal(struct {
 		Config  Config   `json:"config"`
 		Cluster *Cluster `json:"cluster"`
@@ -470,6 +626,9 @@ func (c *Client) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.cluster = temp.Cluster
 	c.config = temp.Config
 	return nil
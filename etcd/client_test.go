@@ -1,22 +1,37 @@
 package etcd
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
 )
 
-// To pass this test, we need to create a cluster of 3 machines
-// The server should be listening on localhost:4001, 4002, 4003
+// newTestClient returns a client of a fresh in-process cluster,
+// which is shut down once the test finishes.
+func newTestClient(t testing.TB) *Client {
+	cluster := etcdtest.NewCluster(1)
+	t.Cleanup(cluster.Close)
+
+	return NewClient(cluster.URLs())
+}
+
 func TestSync(t *testing.T) {
-	fmt.Println("Make sure there are three nodes at 0.0.0.0:4001-4003")
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
 
 	// Explicit trailing slash to ensure this doesn't reproduce:
 	// https://github.com/coreos/go-etcd/issues/82
-	c := NewClient([]string{"http://127.0.0.1:4001/"})
+	c := NewClient([]string{cluster.URLs()[0] + "/"})
 
 	success := c.SyncCluster()
 	if !success {
@@ -36,11 +51,15 @@ func TestSync(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if host != "localhost" {
-			t.Fatal("Host must be localhost")
+		if host != "127.0.0.1" {
+			t.Fatal("Host must be 127.0.0.1")
 		}
 	}
 
+	if len(c.GetCluster()) != len(cluster.Members) {
+		t.Fatalf("synced machines = %v, want %v", c.GetCluster(), cluster.URLs())
+	}
+
 	badMachines := []string{"abc", "edef"}
 
 	success = c.SetCluster(badMachines)
@@ -49,7 +68,7 @@ func TestSync(t *testing.T) {
 		t.Fatal("should not sync on bad machines")
 	}
 
-	goodMachines := []string{"127.0.0.1:4002"}
+	goodMachines := []string{cluster.URLs()[1]}
 
 	success = c.SetCluster(goodMachines)
 
@@ -62,7 +81,7 @@ func TestSync(t *testing.T) {
 }
 
 func TestPersistence(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	c.SyncCluster()
 
 	fo, err := os.Create("config.json")
@@ -96,7 +115,10 @@ func TestPersistence(t *testing.T) {
 }
 
 func TestClientRetry(t *testing.T) {
-	c := NewClient([]string{"http://strange", "http://127.0.0.1:4001"})
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient([]string{"http://strange", cluster.URLs()[0]})
 	// use first endpoint as the picked url
 	c.cluster.picked = 0
 	if _, err := c.Set("foo", "bar", 5); err != nil {
@@ -106,3 +128,100 @@ func TestClientRetry(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClientClose(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// closing while requests are sent is safe
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get("foo", false, false)
+		}()
+	}
+	c.Close()
+	wg.Wait()
+
+	if _, err := c.Get("foo", false, false); err != ErrClientClosed {
+		t.Fatalf("Get = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+// TestClientConcurrency shares one client between goroutines sending
+// requests and goroutines reconfiguring it. Run it with -race.
+func TestClientConcurrency(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var persisted bytes.Buffer
+	mutators := []func(){
+		func() { c.SetConsistency(STRONG_CONSISTENCY) },
+		func() { c.SetConsistency(WEAK_CONSISTENCY) },
+		func() { c.SetCluster(cluster.URLs()) },
+		func() { c.SyncCluster() },
+		func() { c.SetCredentials("user", "pass") },
+		func() { c.SetDialTimeout(time.Second) },
+		func() { c.OpenCURL() },
+		func() { c.CloseCURL() },
+		func() { c.SetTransport(&http.Transport{Dial: c.DefaultDial}) },
+		func() { c.SetPersistence(&persisted) },
+		func() { c.GetCluster() },
+		func() { json.Marshal(c) },
+	}
+
+	var mwg sync.WaitGroup
+	for _, mutate := range mutators {
+		mwg.Add(1)
+		go func(mutate func()) {
+			defer mwg.Done()
+			for ctx.Err() == nil {
+				mutate()
+				time.Sleep(time.Millisecond)
+			}
+		}(mutate)
+	}
+
+	receiver := make(chan *Response)
+	go c.WatchContext(ctx, "stress", 0, true, receiver)
+	go func() {
+		for range receiver {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("stress/%d", i)
+			for j := 0; j < 20; j++ {
+				if _, err := c.Set(key, fmt.Sprint(j), 0); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := c.Get(key, false, false); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	cancel()
+	mwg.Wait()
+}
@@ -1,6 +1,7 @@
 package etcd
 
 import (
+	"encoding/json"
 	"math/rand"
 	"strings"
 	"sync"
@@ -10,7 +11,9 @@ type Cluster struct {
 	Leader   string   `json:"leader"`
 	Machines []string `json:"machines"`
 	picked   int
-	mu       sync.RWMutex
+	// health holds the last known health of the probed machines.
+	health map[string]MemberHealth
+	mu     sync.RWMutex
 }
 
 func NewCluster(machines []string) *Cluster {
@@ -29,26 +32,203 @@ func NewCluster(machines []string) *Cluster {
 	}
 }
 
+// failure moves on to the next healthy machine, or to the next machine if
+// none is healthy.
 func (cl *Cluster) failure() {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
-	cl.picked = (cl.picked + 1) % len(cl.Machines)
+	if next := cl.nextHealthy(); next >= 0 {
+		cl.picked = next
+	} else {
+		cl.picked = (cl.picked + 1) % len(cl.Machines)
+	}
 }
 
+// pick returns the machine in use, after moving on from it if it is
+// unhealthy and another machine is not.
 func (cl *Cluster) pick() string {
+	cl.mu.RLock()
+	machine := cl.Machines[cl.picked]
+	healthy := cl.isHealthy(machine)
+	cl.mu.RUnlock()
+	if healthy {
+		return machine
+	}
+
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
+	if !cl.isHealthy(cl.Machines[cl.picked]) {
+		if next := cl.nextHealthy(); next >= 0 {
+			cl.picked = next
+		}
+	}
 	return cl.Machines[cl.picked]
 }
 
-func (cl *Cluster) updateFromStr(machines string) {
+// nextHealthy returns the index of the first healthy machine after the
+// one in use, or -1 if there is none. cl.mu must be held.
+func (cl *Cluster) nextHealthy() int {
+	n := len(cl.Machines)
+	for i := 1; i < n; i++ {
+		j := (cl.picked + i) % n
+		if cl.isHealthy(cl.Machines[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// isHealthy reports whether machine was healthy when last probed, or was
+// never probed. cl.mu must be held.
+func (cl *Cluster) isHealthy(machine string) bool {
+	h, ok := cl.health[machine]
+	return !ok || h.Healthy
+}
+
+// setHealth records the outcome of probing a machine.
+func (cl *Cluster) setHealth(h MemberHealth) {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
+	if cl.health == nil {
+		cl.health = make(map[string]MemberHealth)
+	}
+	cl.health[h.URL] = h
+}
 
-	cl.Machines = strings.Split(machines, ",")
-	for i := range cl.Machines {
-		cl.Machines[i] = strings.TrimSpace(cl.Machines[i])
+// memberHealth returns the health of every machine.
+func (cl *Cluster) memberHealth() []MemberHealth {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	health := make([]MemberHealth, len(cl.Machines))
+	for i, machine := range cl.Machines {
+		h, ok := cl.health[machine]
+		if !ok {
+			h = MemberHealth{URL: machine, Healthy: true}
+		}
+		health[i] = h
 	}
-	cl.Machines = shuffleStringSlice(cl.Machines)
-	cl.picked = rand.Intn(len(cl.Machines))
+	return health
+}
+
+// machines returns a copy of the machine list.
+func (cl *Cluster) machines() []string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return append([]string(nil), cl.Machines...)
+}
+
+func (cl *Cluster) leader() string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.Leader
+}
+
+func (cl *Cluster) setLeader(leader string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.Leader = leader
+}
+
+// setMemberLeader makes leader the leader if it is one of the machines,
+// and reports whether it is.
+func (cl *Cluster) setMemberLeader(leader string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if !containsString(cl.Machines, leader) {
+		return false
+	}
+	cl.Leader = leader
+	return true
+}
+
+// pickLeader returns the leader if it is known and healthy, or the machine
+// in use otherwise.
+func (cl *Cluster) pickLeader() string {
+	cl.mu.RLock()
+	leader := cl.Leader
+	healthy := leader != "" && cl.isHealthy(leader)
+	cl.mu.RUnlock()
+	if healthy {
+		return leader
+	}
+	return cl.pick()
+}
+
+// leaderFailed forgets the leader if it is the given machine, which failed
+// to answer a request, and reports whether it was.
+func (cl *Cluster) leaderFailed(machine string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if machine == "" || machine != cl.Leader {
+		return false
+	}
+	cl.Leader = ""
+	return true
+}
+
+// updateFromStr replaces the machines with the comma-separated ones, in
+// random order but for those in ranked, which come first and in the order
+// they are ranked.
+func (cl *Cluster) updateFromStr(machines string, ranked []string) {
+	ms := strings.Split(machines, ",")
+	for i := range ms {
+		ms[i] = strings.TrimSpace(ms[i])
+	}
+	ms = shuffleStringSlice(ms)
+
+	var first []string
+	for _, machine := range ranked {
+		if containsString(ms, machine) && !containsString(first, machine) {
+			first = append(first, machine)
+		}
+	}
+	if len(first) == 0 {
+		cl.update(ms, rand.Intn(len(ms)))
+		return
+	}
+	cl.update(append(first, subtractStrings(ms, first)...), 0)
+}
+
+// update replaces the machines, starting with machines[picked].
+func (cl *Cluster) update(machines []string, picked int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	cl.Machines = machines
+	cl.picked = picked
+
+	// forget the machines that left
+	if !containsString(cl.Machines, cl.Leader) {
+		cl.Leader = ""
+	}
+	for machine := range cl.health {
+		if !containsString(cl.Machines, machine) {
+			delete(cl.health, machine)
+		}
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the Marshaller interface
+// as defined by the standard JSON package.
+func (cl *Cluster) MarshalJSON() ([]byte, error) {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	return json.Marshal(struct {
+		Leader   string   `json:"leader"`
+		Machines []string `json:"machines"`
+	}{
+		Leader:   cl.Leader,
+		Machines: cl.Machines,
+	})
 }
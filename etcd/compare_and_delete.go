@@ -1,9 +1,17 @@
 package etcd
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 func (c *Client) CompareAndDelete(key string, prevValue string, prevIndex uint64) (*Response, error) {
-	raw, err := c.RawCompareAndDelete(key, prevValue, prevIndex)
+	return c.CompareAndDeleteContext(context.Background(), key, prevValue, prevIndex)
+}
+
+// CompareAndDeleteContext is like CompareAndDelete but gives up once ctx is done.
+func (c *Client) CompareAndDeleteContext(ctx context.Context, key string, prevValue string, prevIndex uint64) (*Response, error) {
+	raw, err := c.rawCompareAndDelete(ctx, key, prevValue, prevIndex)
 	if err != nil {
 		return nil, err
 	}
@@ -12,6 +20,10 @@ func (c *Client) CompareAndDelete(key string, prevValue string, prevIndex uint64
 }
 
 func (c *Client) RawCompareAndDelete(key string, prevValue string, prevIndex uint64) (*RawResponse, error) {
+	return c.rawCompareAndDelete(context.Background(), key, prevValue, prevIndex)
+}
+
+func (c *Client) rawCompareAndDelete(ctx context.Context, key string, prevValue string, prevIndex uint64) (*RawResponse, error) {
 	if prevValue == "" && prevIndex == 0 {
 		return nil, fmt.Errorf("You must give either prevValue or prevIndex.")
 	}
@@ -24,7 +36,7 @@ func (c *Client) RawCompareAndDelete(key string, prevValue string, prevIndex uin
 		options["prevIndex"] = prevIndex
 	}
 
-	raw, err := c.delete(key, options)
+	raw, err := c.delete(ctx, key, options)
 
 	if err != nil {
 		return nil, err
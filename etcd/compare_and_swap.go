@@ -1,10 +1,19 @@
 package etcd
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 func (c *Client) CompareAndSwap(key string, value string, ttl uint64,
 	prevValue string, prevIndex uint64) (*Response, error) {
-	raw, err := c.RawCompareAndSwap(key, value, ttl, prevValue, prevIndex)
+	return c.CompareAndSwapContext(context.Background(), key, value, ttl, prevValue, prevIndex)
+}
+
+// CompareAndSwapContext is like CompareAndSwap but gives up once ctx is done.
+func (c *Client) CompareAndSwapContext(ctx context.Context, key string, value string, ttl uint64,
+	prevValue string, prevIndex uint64) (*Response, error) {
+	raw, err := c.rawCompareAndSwap(ctx, key, value, ttl, prevValue, prevIndex)
 	if err != nil {
 		return nil, err
 	}
@@ -13,6 +22,11 @@ func (c *Client) CompareAndSwap(key string, value string, ttl uint64,
 }
 
 func (c *Client) RawCompareAndSwap(key string, value string, ttl uint64,
+	prevValue string, prevIndex uint64) (*RawResponse, error) {
+	return c.rawCompareAndSwap(context.Background(), key, value, ttl, prevValue, prevIndex)
+}
+
+func (c *Client) rawCompareAndSwap(ctx context.Context, key string, value string, ttl uint64,
 	prevValue string, prevIndex uint64) (*RawResponse, error) {
 	if prevValue == "" && prevIndex == 0 {
 		return nil, fmt.Errorf("You must give either prevValue or prevIndex.")
@@ -26,7 +40,7 @@ func (c *Client) RawCompareAndSwap(key string, value string, ttl uint64,
 		options["prevIndex"] = prevIndex
 	}
 
-	raw, err := c.put(key, value, ttl, options)
+	raw, err := c.put(ctx, key, value, ttl, options)
 
 	if err != nil {
 		return nil, err
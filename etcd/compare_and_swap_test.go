@@ -5,7 +5,7 @@ import (
 )
 
 func TestCompareAndSwap(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("foo", true)
 	}()
@@ -0,0 +1,199 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultConfigDebounce is how long a ConfigLoader waits for a burst of
+// changes to end before reloading, unless told otherwise.
+const DefaultConfigDebounce = 100 * time.Millisecond
+
+// ConfigReload tells the outcome of a reload by a ConfigLoader.
+type ConfigReload struct {
+	// Config is the configuration in use after the reload: the new one if
+	// it loaded, and the previous one otherwise.
+	Config interface{}
+	// Index is the etcd index the configuration in use was read at.
+	Index uint64
+	// Err is why the new configuration was rejected, if it was.
+	Err error
+}
+
+// ConfigLoader loads a configuration struct from the keys under a prefix,
+// as mapped by UnmarshalNode, and loads it again whenever they change.
+//
+// Each version of the configuration is a new value, checked by a validate
+// function before it replaces the previous one; an invalid version is
+// reported and the previous one kept. Changes are handled once no other
+// change was seen for the debounce delay, so that a burst of changes
+// results in a single reload.
+type ConfigLoader struct {
+	client    *Client
+	prefix    string
+	newConfig func() interface{}
+	validate  func(interface{}) error
+
+	// Debounce is how long to wait after a change for more changes before
+	// reloading. It must be set before Run is called.
+	Debounce time.Duration
+
+	mu      sync.Mutex
+	current interface{}
+	index   uint64
+	subs    []chan ConfigReload
+	stopped bool
+}
+
+// NewConfigLoader returns a loader of the configuration under prefix.
+// newConfig returns a pointer to a new configuration holding its
+// defaults, which the keys under prefix then override. validate, if not
+// nil, rejects invalid configurations with an error.
+func NewConfigLoader(client *Client, prefix string, newConfig func() interface{},
+	validate func(interface{}) error) *ConfigLoader {
+	return &ConfigLoader{
+		client:    client,
+		prefix:    prefix,
+		newConfig: newConfig,
+		validate:  validate,
+		Debounce:  DefaultConfigDebounce,
+	}
+}
+
+// Run loads the configuration, and then loads it again on every change
+// until ctx is done, or until watching the prefix fails for good. It
+// returns ctx.Err() in the former case. If the first configuration cannot
+// be loaded, Run returns why right away.
+//
+// Run must only be called once. The channels returned by Subscribe are
+// closed when it returns.
+func (l *ConfigLoader) Run(ctx context.Context) error {
+	defer l.stop()
+
+	index, err := l.reload(ctx)
+	if err != nil {
+		return err
+	}
+
+	changes := make(chan error)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		w := l.client.NewWatcher(l.prefix, index+1, true)
+		for {
+			_, err := w.Next(ctx)
+			select {
+			case changes <- err:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// the timer restarts on every change, and is pending until it fires
+	debounce := time.NewTimer(l.Debounce)
+	defer debounce.Stop()
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	pending := false
+	for {
+		select {
+		case err := <-changes:
+			if err != nil {
+				return err
+			}
+			if pending && !debounce.Stop() {
+				<-debounce.C
+			}
+			debounce.Reset(l.Debounce)
+			pending = true
+		case <-debounce.C:
+			pending = false
+			if _, err := l.reload(ctx); err != nil && ctx.Err() == nil {
+				logger.Warningf("config: cannot reload %s: %v", l.prefix, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Current returns the configuration in use, or nil until the first one is
+// loaded. It must not be modified.
+func (l *ConfigLoader) Current() interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// Subscribe returns a channel receiving the outcome of every reload. A
+// subscriber falling behind only receives the latest outcome.
+func (l *ConfigLoader) Subscribe() <-chan ConfigReload {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan ConfigReload, 1)
+	if l.stopped {
+		close(ch)
+	} else {
+		l.subs = append(l.subs, ch)
+	}
+	return ch
+}
+
+// reload loads the configuration and, if valid, makes it the one in use.
+// It returns the index the configuration was read at.
+func (l *ConfigLoader) reload(ctx context.Context) (uint64, error) {
+	resp, err := l.client.GetContext(ctx, l.prefix, false, true)
+	if IsKeyNotFound(err) {
+		// no keys at all leaves the defaults
+		resp, err = &Response{EtcdIndex: errorIndex(err)}, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	config := l.newConfig()
+	if resp.Node != nil {
+		err = UnmarshalNode(resp.Node, config)
+	}
+	if err == nil && l.validate != nil {
+		err = l.validate(config)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		l.current, l.index = config, resp.EtcdIndex
+	}
+	l.publish(ConfigReload{Config: l.current, Index: l.index, Err: err})
+	return resp.EtcdIndex, err
+}
+
+// publish sends r to every subscriber, in place of any outcome they have
+// not received yet. It must hold l.mu.
+func (l *ConfigLoader) publish(r ConfigReload) {
+	for _, ch := range l.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- r
+	}
+}
+
+func (l *ConfigLoader) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopped = true
+	for _, ch := range l.subs {
+		close(ch)
+	}
+	l.subs = nil
+}
@@ -0,0 +1,144 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+type loaderConfig struct {
+	Port    int      `etcd:"port"`
+	Host    string   `etcd:"host"`
+	Workers int      `etcd:"workers"`
+	Tags    []string `etcd:"tags"`
+}
+
+func newLoaderConfig() interface{} {
+	return &loaderConfig{Host: "localhost", Workers: 1}
+}
+
+func validateLoaderConfig(v interface{}) error {
+	if v.(*loaderConfig).Port == 0 {
+		return errors.New("port is not set")
+	}
+	return nil
+}
+
+func nextReload(t *testing.T, reloads <-chan ConfigReload) ConfigReload {
+	t.Helper()
+	select {
+	case r := <-reloads:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload")
+	}
+	panic("unreachable")
+}
+
+func TestConfigLoader(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("/app/port", "8080", 0)
+
+	l := NewConfigLoader(c, "/app", newLoaderConfig, validateLoaderConfig)
+	l.Debounce = 50 * time.Millisecond
+	reloads := l.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Run(ctx) }()
+
+	r := nextReload(t, reloads)
+	first := r.Config.(*loaderConfig)
+	if r.Err != nil || first.Port != 8080 || first.Host != "localhost" || first.Workers != 1 {
+		t.Fatalf("reload = %+v, config %+v", r, first)
+	}
+	if l.Current() != r.Config {
+		t.Fatal("Current is not the loaded configuration")
+	}
+
+	// a burst of changes results in a single reload
+	c.Set("/app/host", "example.com", 0)
+	c.Set("/app/workers", "4", 0)
+	c.Set("/app/tags/0", "a", 0)
+	r = nextReload(t, reloads)
+	cfg := r.Config.(*loaderConfig)
+	if r.Err != nil || cfg.Host != "example.com" || cfg.Workers != 4 || len(cfg.Tags) != 1 {
+		t.Fatalf("reload = %+v, config %+v", r, cfg)
+	}
+	if first.Host != "localhost" {
+		t.Fatal("the previous configuration was modified")
+	}
+	select {
+	case r := <-reloads:
+		t.Fatalf("second reload %+v for a single burst", r)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// invalid configurations are reported and not used
+	c.Delete("/app/port", false)
+	r = nextReload(t, reloads)
+	if r.Err == nil || r.Config != cfg || l.Current() != cfg {
+		t.Fatalf("reload = %+v, want an error and the previous configuration", r)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v, want %v", err, context.Canceled)
+	}
+	if _, ok := <-reloads; ok {
+		t.Fatal("subscription still open after Run returned")
+	}
+}
+
+func TestConfigLoaderDebounce(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("/app/port", "1", 0)
+
+	l := NewConfigLoader(c, "/app", newLoaderConfig, validateLoaderConfig)
+	l.Debounce = 100 * time.Millisecond
+	reloads := l.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Run(ctx)
+	nextReload(t, reloads)
+
+	// a burst lasting several times the debounce delay results in a single
+	// reload once it ends
+	for port := 2; port <= 25; port++ {
+		c.Set("/app/port", strconv.Itoa(port), 0)
+		time.Sleep(20 * time.Millisecond)
+		select {
+		case r := <-reloads:
+			t.Fatalf("reload %+v during the burst", r)
+		default:
+		}
+	}
+	r := nextReload(t, reloads)
+	if port := r.Config.(*loaderConfig).Port; r.Err != nil || port != 25 {
+		t.Fatalf("reload = %+v, port %d, want port 25", r, port)
+	}
+}
+
+func TestConfigLoaderInvalidAtStart(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	l := NewConfigLoader(NewClient(cluster.URLs()), "/app", newLoaderConfig, validateLoaderConfig)
+	if err := l.Run(context.Background()); err == nil || err.Error() != "port is not set" {
+		t.Fatalf("Run = %v, want the validation error", err)
+	}
+	if l.Current() != nil {
+		t.Fatalf("Current = %+v, want nil", l.Current())
+	}
+}
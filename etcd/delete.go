@@ -1,5 +1,7 @@
 package etcd
 
+import "context"
+
 // Delete deletes the given key.
 //
 // When recursive set to false, if the key points to a
@@ -10,7 +12,12 @@ package etcd
 // then everything under the directory (including all child directories)
 // will be deleted.
 func (c *Client) Delete(key string, recursive bool) (*Response, error) {
-	raw, err := c.RawDelete(key, recursive, false)
+	return c.DeleteContext(context.Background(), key, recursive)
+}
+
+// DeleteContext is like Delete but gives up once ctx is done.
+func (c *Client) DeleteContext(ctx context.Context, key string, recursive bool) (*Response, error) {
+	raw, err := c.rawDelete(ctx, key, recursive, false)
 
 	if err != nil {
 		return nil, err
@@ -21,7 +28,12 @@ func (c *Client) Delete(key string, recursive bool) (*Response, error) {
 
 // DeleteDir deletes an empty directory or a key value pair
 func (c *Client) DeleteDir(key string) (*Response, error) {
-	raw, err := c.RawDelete(key, false, true)
+	return c.DeleteDirContext(context.Background(), key)
+}
+
+// DeleteDirContext is like DeleteDir but gives up once ctx is done.
+func (c *Client) DeleteDirContext(ctx context.Context, key string) (*Response, error) {
+	raw, err := c.rawDelete(ctx, key, false, true)
 
 	if err != nil {
 		return nil, err
@@ -31,10 +43,14 @@ func (c *Client) DeleteDir(key string) (*Response, error) {
 }
 
 func (c *Client) RawDelete(key string, recursive bool, dir bool) (*RawResponse, error) {
+	return c.rawDelete(context.Background(), key, recursive, dir)
+}
+
+func (c *Client) rawDelete(ctx context.Context, key string, recursive bool, dir bool) (*RawResponse, error) {
 	ops := Options{
 		"recursive": recursive,
 		"dir":       dir,
 	}
 
-	return c.delete(key, ops)
+	return c.delete(ctx, key, ops)
 }
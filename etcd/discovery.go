@@ -0,0 +1,164 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Errors introduced by Discovery.
+var (
+	ErrDiscoverySizeNotFound = errors.New("discovery: size of the cluster is not configured")
+	ErrDiscoveryFull         = errors.New("discovery: cluster is full")
+	ErrDiscoveryDuplicateID  = errors.New("discovery: member is registered already")
+)
+
+// discoveryConfigDir holds the settings of a discovery directory.
+const discoveryConfigDir = "_config"
+
+// Discovery bootstraps a cluster through a discovery directory, as used
+// by etcd members started with a discovery URL.
+//
+// The expected number of members is stored under _config/size in the
+// directory, and each member registers itself with a key named after its
+// ID, holding its peer URLs in the form etcd expects for its initial
+// cluster, such as "node1=http://10.0.0.1:2380". The cluster is made of
+// the first members to register, in order; those coming later are turned
+// away.
+type Discovery struct {
+	client *Client
+	dir    string
+}
+
+// DiscoveryMember is a member registered in a discovery directory.
+type DiscoveryMember struct {
+	ID    string
+	Value string
+}
+
+// NewDiscovery returns a helper for the discovery directory dir, on the
+// cluster client talks to.
+func NewDiscovery(client *Client, dir string) *Discovery {
+	return &Discovery{client: client, dir: dir}
+}
+
+// Size returns the number of members the cluster is expected to have.
+func (d *Discovery) Size(ctx context.Context) (int, error) {
+	resp, err := d.client.GetContext(ctx, path.Join(d.dir, discoveryConfigDir, "size"), false, false)
+	if err != nil {
+		if IsKeyNotFound(err) {
+			return 0, ErrDiscoverySizeNotFound
+		}
+		return 0, err
+	}
+	size, err := strconv.Atoi(resp.Node.Value)
+	if err != nil || size <= 0 {
+		return 0, ErrDiscoverySizeNotFound
+	}
+	return size, nil
+}
+
+// Join registers a member and waits for the cluster to be complete, then
+// returns its members in the order they registered, the joining member
+// included. It fails with ErrDiscoveryFull if the cluster was complete
+// before the member could register.
+func (d *Discovery) Join(ctx context.Context, id, value string) ([]DiscoveryMember, error) {
+	size, err := d.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, _, err := d.members(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ID == id {
+			return nil, ErrDiscoveryDuplicateID
+		}
+	}
+	if len(members) >= size {
+		return nil, ErrDiscoveryFull
+	}
+
+	if err := d.Register(ctx, id, value); err != nil {
+		return nil, err
+	}
+	if members, err = d.Wait(ctx, size); err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ID == id {
+			return members, nil
+		}
+	}
+	// others registered first while this member was about to
+	return nil, ErrDiscoveryFull
+}
+
+// Register registers a member without waiting for the others.
+func (d *Discovery) Register(ctx context.Context, id, value string) error {
+	_, err := d.client.CreateContext(ctx, path.Join(d.dir, id), value, 0)
+	if IsNodeExist(err) {
+		return ErrDiscoveryDuplicateID
+	}
+	return err
+}
+
+// Wait blocks until at least size members are registered, or ctx is
+// done, and then returns the first size of them.
+func (d *Discovery) Wait(ctx context.Context, size int) ([]DiscoveryMember, error) {
+	for {
+		members, index, err := d.members(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(members) >= size {
+			return members[:size], nil
+		}
+
+		_, err = d.client.WatchContext(ctx, d.dir, index+1, true, nil)
+		if err != nil && !IsIndexCleared(err) {
+			return nil, err
+		}
+	}
+}
+
+// members returns the registered members in the order they registered,
+// and the index they were read at.
+func (d *Discovery) members(ctx context.Context) ([]DiscoveryMember, uint64, error) {
+	resp, err := d.client.GetContext(ctx, d.dir, false, false)
+	if IsKeyNotFound(err) {
+		// nobody registered yet
+		return nil, errorIndex(err), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nodes := make(Nodes, 0, len(resp.Node.Nodes))
+	for _, node := range resp.Node.Nodes {
+		if !node.Dir {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].CreatedIndex < nodes[j].CreatedIndex })
+
+	members := make([]DiscoveryMember, len(nodes))
+	for i, node := range nodes {
+		members[i] = DiscoveryMember{ID: path.Base(node.Key), Value: node.Value}
+	}
+	return members, resp.EtcdIndex, nil
+}
+
+// InitialCluster joins the values of members into the list etcd takes as
+// its initial cluster.
+func InitialCluster(members []DiscoveryMember) string {
+	values := make([]string, len(members))
+	for i, m := range members {
+		values[i] = m.Value
+	}
+	return strings.Join(values, ",")
+}
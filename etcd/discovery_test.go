@@ -0,0 +1,79 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestDiscoveryJoin(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	d := NewDiscovery(c, "/discovery/token")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := d.Join(ctx, "a", "a=http://10.0.0.1:2380"); err != ErrDiscoverySizeNotFound {
+		t.Fatalf("Join = %v, want %v", err, ErrDiscoverySizeNotFound)
+	}
+	if _, err := c.Set("/discovery/token/_config/size", "3", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		members []DiscoveryMember
+		err     error
+	}
+	results := make(chan result, 3)
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("m%d", i)
+		value := fmt.Sprintf("node%d=http://10.0.0.%d:2380", i, i)
+		go func() {
+			members, err := NewDiscovery(NewClient(cluster.URLs()), "/discovery/token").Join(ctx, id, value)
+			results <- result{members, err}
+		}()
+		// members register in order; _config is hidden
+		waitForNodes(t, c, "/discovery/token", i)
+	}
+
+	var first []DiscoveryMember
+	for i := 0; i < 3; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if first == nil {
+			first = r.members
+		} else if !reflect.DeepEqual(r.members, first) {
+			t.Fatalf("members = %v, want %v as seen by another member", r.members, first)
+		}
+	}
+	want := "node1=http://10.0.0.1:2380,node2=http://10.0.0.2:2380,node3=http://10.0.0.3:2380"
+	if got := InitialCluster(first); got != want {
+		t.Fatalf("initial cluster = %q, want %q", got, want)
+	}
+
+	if _, err := d.Join(ctx, "m4", "node4=http://10.0.0.4:2380"); err != ErrDiscoveryFull {
+		t.Fatalf("Join = %v, want %v", err, ErrDiscoveryFull)
+	}
+}
+
+func TestDiscoveryRegisterTwice(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	d := NewDiscovery(NewClient(cluster.URLs()), "/discovery/token")
+	ctx := context.Background()
+	if err := d.Register(ctx, "a", "a=http://10.0.0.1:2380"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Register(ctx, "a", "a=http://10.0.0.1:2380"); err != ErrDiscoveryDuplicateID {
+		t.Fatalf("Register = %v, want %v", err, ErrDiscoveryDuplicateID)
+	}
+}
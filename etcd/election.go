@@ -0,0 +1,302 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors introduced by Election.
+var (
+	ErrElectionNotLeader = errors.New("election: not the leader")
+	ErrElectionNoLeader  = errors.New("election: no leader")
+)
+
+// electionRetryDelay is how long Observe waits before retrying after an
+// error.
+const electionRetryDelay = time.Second
+
+// Election elects a single leader among the clients campaigning for the
+// same key.
+//
+// The leader is whoever manages to create the key, and the key's value
+// identifies it to the others. The key is created with a TTL and kept
+// alive by the leader with CompareAndSwap on its modified index, so that
+// a leader that dies is replaced once the key expires, and a leader that
+// failed to refresh the key in time finds out through Lost.
+type Election struct {
+	client *Client
+	key    string
+	ttl    uint64
+
+	mu   sync.Mutex
+	term *term
+	lost chan struct{}
+}
+
+// term is the state of a won election, until it is resigned or lost.
+type term struct {
+	value string
+	index uint64
+	// refreshed is when the last successful refresh of the key was sent,
+	// so that the key has expired on the server by refreshed plus the TTL.
+	refreshed time.Time
+	stop      context.CancelFunc
+	done      chan struct{}
+	lost      chan struct{}
+}
+
+// NewElection returns an election for the given key. The key expires after
+// ttl seconds unless refreshed by the leader; a ttl of 0 makes it
+// permanent.
+func NewElection(client *Client, key string, ttl uint64) *Election {
+	return &Election{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+	}
+}
+
+// Campaign blocks until the election is won with the given value, or ctx is
+// done. The leadership must be resigned before campaigning again.
+func (e *Election) Campaign(ctx context.Context, value string) error {
+	for {
+		sent := time.Now()
+		resp, err := e.client.CreateContext(ctx, e.key, value, e.ttl)
+		if err == nil {
+			e.elected(value, resp.Node.ModifiedIndex, sent)
+			return nil
+		}
+		if !IsNodeExist(err) {
+			return err
+		}
+
+		// Someone else leads; wait for them to go.
+		if err := e.client.waitDelete(ctx, e.key, errorIndex(err)+1); err != nil {
+			return err
+		}
+	}
+}
+
+// Resign gives up the leadership so that another candidate can be elected.
+// It fails with ErrElectionNotLeader if the leadership has been lost.
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	t := e.term
+	e.term = nil
+	e.mu.Unlock()
+
+	if t == nil {
+		return ErrElectionNotLeader
+	}
+
+	t.stop()
+	<-t.done
+
+	_, err := e.client.CompareAndDeleteContext(ctx, e.key, "", t.index)
+	if IsCompareFailed(err) || IsKeyNotFound(err) {
+		return ErrElectionNotLeader
+	}
+	return err
+}
+
+// IsLeader reports whether the election was won and has neither been
+// resigned nor lost since.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.term != nil
+}
+
+// Lost returns a channel that is closed when the leadership won by the
+// last successful Campaign is lost, because the key could not be refreshed
+// before it expired or was changed by someone else. It is not closed by
+// Resign.
+func (e *Election) Lost() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lost
+}
+
+// Leader returns the value of the current leader, or ErrElectionNoLeader if
+// there is none.
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	resp, err := e.client.GetContext(ctx, e.key, false, false)
+	if IsKeyNotFound(err) {
+		return "", ErrElectionNoLeader
+	}
+	if err != nil {
+		return "", err
+	}
+	return resp.Node.Value, nil
+}
+
+// Observe returns a channel receiving the value of the current leader, and
+// then that of every new leader until ctx is done. An empty value means
+// there is no leader. The channel is closed once ctx is done.
+func (e *Election) Observe(ctx context.Context) <-chan string {
+	leaders := make(chan string)
+	go e.observe(ctx, leaders)
+	return leaders
+}
+
+func (e *Election) elected(value string, index uint64, sent time.Time) {
+	refreshCtx, stop := context.WithCancel(context.Background())
+	t := &term{
+		value:     value,
+		index:     index,
+		refreshed: sent,
+		stop:      stop,
+		done:      make(chan struct{}),
+		lost:      make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	e.term, e.lost = t, t.lost
+	e.mu.Unlock()
+
+	go e.refresh(refreshCtx, t)
+}
+
+// refresh renews the TTL of the key until ctx is cancelled or the
+// leadership is lost. The leadership is lost when the key was changed by
+// someone else, or when it could not be refreshed, for whatever reason,
+// before it expired on the server.
+func (e *Election) refresh(ctx context.Context, t *term) {
+	defer close(t.done)
+
+	if e.ttl == 0 {
+		return
+	}
+
+	ttl := time.Duration(e.ttl) * time.Second
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	expired := time.NewTimer(ttl)
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// a refresh arriving after the key expired is of no use
+			expiry := t.refreshed.Add(ttl)
+			refreshCtx, cancel := context.WithDeadline(ctx, expiry)
+			sent := time.Now()
+			resp, err := e.client.CompareAndSwapContext(refreshCtx, e.key, t.value, e.ttl, "", t.index)
+			cancel()
+			if err == nil {
+				t.index, t.refreshed = resp.Node.ModifiedIndex, sent
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.Warningf("election: cannot refresh %s: %v", e.key, err)
+			if IsCompareFailed(err) || IsKeyNotFound(err) || !time.Now().Before(expiry) {
+				e.lose(t)
+				return
+			}
+		case <-expired.C:
+			if left := time.Until(t.refreshed.Add(ttl)); left > 0 {
+				expired.Reset(left)
+				continue
+			}
+			logger.Warningf("election: %s expired before it could be refreshed", e.key)
+			e.lose(t)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Election) lose(t *term) {
+	e.mu.Lock()
+	if e.term == t {
+		e.term = nil
+	}
+	e.mu.Unlock()
+
+	t.stop()
+	close(t.lost)
+}
+
+func (e *Election) observe(ctx context.Context, leaders chan<- string) {
+	defer close(leaders)
+
+	sent, last := false, ""
+	send := func(value string) bool {
+		if sent && value == last {
+			return true
+		}
+		select {
+		case leaders <- value:
+			sent, last = true, value
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		err := e.observeOnce(ctx, send)
+		if ctx.Err() != nil {
+			return
+		}
+		if !IsIndexCleared(err) {
+			logger.Warningf("election: cannot observe %s: %v", e.key, err)
+
+			select {
+			case <-time.After(electionRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// observeOnce sends the current leader and follows the changes of the key
+// until an error occurs.
+func (e *Election) observeOnce(ctx context.Context, send func(string) bool) error {
+	var index uint64
+
+	resp, err := e.client.GetContext(ctx, e.key, false, false)
+	switch {
+	case err == nil:
+		if !send(resp.Node.Value) {
+			return ctx.Err()
+		}
+		index = resp.EtcdIndex + 1
+	case IsKeyNotFound(err):
+		if !send("") {
+			return ctx.Err()
+		}
+		index = errorIndex(err) + 1
+	default:
+		return err
+	}
+
+	for {
+		raw, err := e.client.watchOnce(ctx, e.key, index, false)
+		if err != nil {
+			return err
+		}
+		resp, err := raw.Unmarshal()
+		if err != nil {
+			return err
+		}
+
+		value := resp.Node.Value
+		if Action(resp.Action).removesNode() {
+			value = ""
+		}
+		if !send(value) {
+			return ctx.Err()
+		}
+		index = resp.Node.ModifiedIndex + 1
+	}
+}
@@ -0,0 +1,123 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestElection(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e1 := NewElection(NewClient(cluster.URLs()), "/leader", 0)
+	e2 := NewElection(NewClient(cluster.URLs()), "/leader", 0)
+
+	if _, err := e1.Leader(ctx); err != ErrElectionNoLeader {
+		t.Fatalf("Leader = %v, want %v", err, ErrElectionNoLeader)
+	}
+	if err := e1.Campaign(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if leader, err := e2.Leader(ctx); err != nil || leader != "a" {
+		t.Fatalf("Leader = %q, %v, want a", leader, err)
+	}
+
+	leaders := e2.Observe(ctx)
+	expectLeader(t, leaders, "a")
+
+	elected := make(chan error)
+	go func() { elected <- e2.Campaign(ctx, "b") }()
+
+	select {
+	case err := <-elected:
+		t.Fatalf("Campaign returned %v while another candidate led", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := e1.Resign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-elected; err != nil {
+		t.Fatal(err)
+	}
+	if e1.IsLeader() || !e2.IsLeader() {
+		t.Fatalf("IsLeader = %v, %v, want false, true", e1.IsLeader(), e2.IsLeader())
+	}
+	expectLeader(t, leaders, "")
+	expectLeader(t, leaders, "b")
+
+	cancel()
+	for range leaders {
+	}
+}
+
+func TestElectionLost(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx := context.Background()
+	e := NewElection(c, "/leader", 1)
+	if err := e.Campaign(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the key outlives its TTL while refreshed
+	time.Sleep(1500 * time.Millisecond)
+	if !e.IsLeader() {
+		t.Fatal("leadership lost while refreshed")
+	}
+
+	if _, err := c.Set("/leader", "usurper", 0); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-e.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("leadership loss was not reported")
+	}
+	if e.IsLeader() {
+		t.Fatal("IsLeader = true after the leadership was lost")
+	}
+	if err := e.Resign(ctx); err != ErrElectionNotLeader {
+		t.Fatalf("Resign = %v, want %v", err, ErrElectionNotLeader)
+	}
+}
+
+func TestElectionLostUnreachable(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx := context.Background()
+	e := NewElection(NewClient(cluster.URLs()), "/leader", 1)
+	if err := e.Campaign(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a leader cut off from the cluster steps down once the key expires
+	cluster.Members[0].Kill()
+	select {
+	case <-e.Lost():
+	case <-time.After(3 * time.Second):
+		t.Fatal("leadership loss was not reported")
+	}
+	if e.IsLeader() {
+		t.Fatal("IsLeader = true after the key expired")
+	}
+}
+
+func expectLeader(t *testing.T, leaders <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case leader := <-leaders:
+		if leader != want {
+			t.Fatalf("leader = %q, want %q", leader, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("leader %q was not observed", want)
+	}
+}
@@ -1,18 +1,103 @@
 package etcd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
 )
 
+// Error codes introduced by the client.
 const (
 	ErrCodeEtcdNotReachable    = 501
 	ErrCodeUnhandledHTTPStatus = 502
 )
 
+// Error codes sent by etcd.
+const (
+	ErrCodeKeyNotFound      = 100
+	ErrCodeTestFailed       = 101
+	ErrCodeNotFile          = 102
+	ErrCodeNoMorePeer       = 103
+	ErrCodeNotDir           = 104
+	ErrCodeNodeExist        = 105
+	ErrCodeKeyIsPreserved   = 106
+	ErrCodeRootROnly        = 107
+	ErrCodeDirNotEmpty      = 108
+	ErrCodeExistingPeerAddr = 109
+	ErrCodeUnauthorized     = 110
+
+	ErrCodeValueRequired        = 200
+	ErrCodePrevValueRequired    = 201
+	ErrCodeTTLNaN               = 202
+	ErrCodeIndexNaN             = 203
+	ErrCodeValueOrTTLRequired   = 204
+	ErrCodeTimeoutNaN           = 205
+	ErrCodeNameRequired         = 206
+	ErrCodeIndexOrValueRequired = 207
+	ErrCodeIndexValueMutex      = 208
+	ErrCodeInvalidField         = 209
+	ErrCodeInvalidForm          = 210
+	ErrCodeRefreshValue         = 211
+	ErrCodeRefreshTTLRequired   = 212
+
+	ErrCodeRaftInternal = 300
+	ErrCodeLeaderElect  = 301
+
+	ErrCodeWatcherCleared     = 400
+	ErrCodeEventIndexCleared  = 401
+	ErrCodeStandbyInternal    = 402
+	ErrCodeInvalidActiveSize  = 403
+	ErrCodeInvalidRemoveDelay = 404
+
+	ErrCodeClientInternal = 500
+)
+
 var (
 	errorMap = map[int]string{
-		ErrCodeEtcdNotReachable: "All the given peers are not reachable",
+		ErrCodeEtcdNotReachable:    "All the given peers are not reachable",
+		ErrCodeUnhandledHTTPStatus: "Unhandled HTTP status",
+
+		ErrCodeKeyNotFound:      "Key not found",
+		ErrCodeTestFailed:       "Compare failed",
+		ErrCodeNotFile:          "Not a file",
+		ErrCodeNoMorePeer:       "Reached the max number of peers in the cluster",
+		ErrCodeNotDir:           "Not a directory",
+		ErrCodeNodeExist:        "Key already exists",
+		ErrCodeKeyIsPreserved:   "The prefix of given key is a keyword in etcd",
+		ErrCodeRootROnly:        "Root is read only",
+		ErrCodeDirNotEmpty:      "Directory not empty",
+		ErrCodeExistingPeerAddr: "Peer address has existed",
+		ErrCodeUnauthorized:     "The request requires user authentication",
+
+		ErrCodeValueRequired:        "Value is Required in POST form",
+		ErrCodePrevValueRequired:    "PrevValue is Required in POST form",
+		ErrCodeTTLNaN:               "The given TTL in POST form is not a number",
+		ErrCodeIndexNaN:             "The given index in POST form is not a number",
+		ErrCodeValueOrTTLRequired:   "Value or TTL is required in POST form",
+		ErrCodeTimeoutNaN:           "The given timeout in POST form is not a number",
+		ErrCodeNameRequired:         "Name is required in POST form",
+		ErrCodeIndexOrValueRequired: "Index or value is required",
+		ErrCodeIndexValueMutex:      "Index and value cannot both be specified",
+		ErrCodeInvalidField:         "Invalid field",
+		ErrCodeInvalidForm:          "Invalid POST form",
+		ErrCodeRefreshValue:         "Value provided on refresh",
+		ErrCodeRefreshTTLRequired:   "A TTL must be provided on refresh",
+
+		ErrCodeRaftInternal: "Raft Internal Error",
+		ErrCodeLeaderElect:  "During Leader Election",
+
+		ErrCodeWatcherCleared:     "watcher is cleared due to etcd recovery",
+		ErrCodeEventIndexCleared:  "The event in requested index is outdated and cleared",
+		ErrCodeStandbyInternal:    "Standby Internal Error",
+		ErrCodeInvalidActiveSize:  "Invalid active size",
+		ErrCodeInvalidRemoveDelay: "Standby remove delay",
+
+		ErrCodeClientInternal: "Client Internal Error",
 	}
 )
 
@@ -47,3 +132,130 @@ func handleError(b []byte) error {
 
 	return etcdErr
 }
+
+// Is makes errors.Is report an EtcdError as matching any other EtcdError
+// with the same error code, so that
+//
+//	errors.Is(err, &EtcdError{ErrorCode: ErrCodeKeyNotFound})
+//
+// holds for every "key not found" error, whatever its message or index.
+func (e EtcdError) Is(target error) bool {
+	switch t := target.(type) {
+	case *EtcdError:
+		return t != nil && t.ErrorCode == e.ErrorCode
+	case EtcdError:
+		return t.ErrorCode == e.ErrorCode
+	}
+	return false
+}
+
+// HTTPError is returned by the APIs that, unlike the keys API, report
+// failures through the HTTP status and a message only, such as the
+// members API.
+type HTTPError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is makes errors.Is report an HTTPError as matching any other HTTPError
+// with the same status code.
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t != nil && t.StatusCode == e.StatusCode
+}
+
+// newHTTPError builds the error of a response with an unexpected status.
+func newHTTPError(resp *RawResponse) *HTTPError {
+	httpErr := &HTTPError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(resp.Body, httpErr); err != nil {
+		httpErr.Message = strings.TrimSpace(string(resp.Body))
+	}
+	return httpErr
+}
+
+// IsConflict reports whether err means that the request conflicts with
+// the state of the cluster, such as adding a member with a peer URL
+// already in use.
+func IsConflict(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict
+}
+
+// IsUnauthorized reports whether err means that the request was refused
+// because of missing or insufficient credentials.
+func IsUnauthorized(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusUnauthorized
+	}
+	return isErrorCode(err, ErrCodeUnauthorized)
+}
+
+// IsKeyNotFound reports whether err means that the key does not exist.
+func IsKeyNotFound(err error) bool {
+	return isErrorCode(err, ErrCodeKeyNotFound)
+}
+
+// IsCompareFailed reports whether err means that the conditions of a
+// compare-and-swap or compare-and-delete were not met.
+func IsCompareFailed(err error) bool {
+	return isErrorCode(err, ErrCodeTestFailed)
+}
+
+// IsNodeExist reports whether err means that the key was expected not to
+// exist, but does.
+func IsNodeExist(err error) bool {
+	return isErrorCode(err, ErrCodeNodeExist)
+}
+
+// IsIndexCleared reports whether err means that the events a watch waits
+// for are no longer kept by etcd, so that the key must be read again.
+func IsIndexCleared(err error) bool {
+	return isErrorCode(err, ErrCodeEventIndexCleared)
+}
+
+// IsRetryable reports whether the request that failed with err may
+// succeed if sent again later: when the cluster could not be reached, was
+// electing a leader or recovering, or lost the connection halfway. A
+// request given up because its context was cancelled or its deadline
+// passed is not retryable.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var etcdErr *EtcdError
+	if errors.As(err, &etcdErr) {
+		switch etcdErr.ErrorCode {
+		case ErrCodeEtcdNotReachable, ErrCodeRaftInternal, ErrCodeLeaderElect, ErrCodeWatcherCleared:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// isErrorCode reports whether err is an etcd error with the given code.
+func isErrorCode(err error, code int) bool {
+	var etcdErr *EtcdError
+	return errors.As(err, &etcdErr) && etcdErr.ErrorCode == code
+}
+
+// errorIndex returns the etcd index an etcd error was returned at, or 0 if
+// err is not an etcd error.
+func errorIndex(err error) uint64 {
+	var etcdErr *EtcdError
+	if errors.As(err, &etcdErr) {
+		return etcdErr.Index
+	}
+	return 0
+}
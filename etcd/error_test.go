@@ -0,0 +1,78 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		err error
+
+		keyNotFound, compareFailed, nodeExist, indexCleared, retryable bool
+	}{
+		{newError(ErrCodeKeyNotFound, "/foo", 1), true, false, false, false, false},
+		{newError(ErrCodeTestFailed, "[1 != 2]", 1), false, true, false, false, false},
+		{newError(ErrCodeNodeExist, "/foo", 1), false, false, true, false, false},
+		{newError(ErrCodeEventIndexCleared, "", 1), false, false, false, true, false},
+		{newError(ErrCodeEtcdNotReachable, "", 0), false, false, false, false, true},
+		{newError(ErrCodeLeaderElect, "", 0), false, false, false, false, true},
+		{newError(ErrCodeUnhandledHTTPStatus, "", 0), false, false, false, false, false},
+		{fmt.Errorf("get: %w", newError(ErrCodeKeyNotFound, "/foo", 1)), true, false, false, false, false},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, false, false, false, false, true},
+		{io.ErrUnexpectedEOF, false, false, false, false, true},
+		{newError(ErrCodeWatcherCleared, "", 0), false, false, false, false, true},
+		{context.Canceled, false, false, false, false, false},
+		{context.DeadlineExceeded, false, false, false, false, false},
+		{&url.Error{Op: "Get", URL: "http://127.0.0.1:4001", Err: context.DeadlineExceeded}, false, false, false, false, false},
+		{nil, false, false, false, false, false},
+	}
+
+	for i, tt := range tests {
+		if got := IsKeyNotFound(tt.err); got != tt.keyNotFound {
+			t.Errorf("#%d: IsKeyNotFound = %v, want %v", i, got, tt.keyNotFound)
+		}
+		if got := IsCompareFailed(tt.err); got != tt.compareFailed {
+			t.Errorf("#%d: IsCompareFailed = %v, want %v", i, got, tt.compareFailed)
+		}
+		if got := IsNodeExist(tt.err); got != tt.nodeExist {
+			t.Errorf("#%d: IsNodeExist = %v, want %v", i, got, tt.nodeExist)
+		}
+		if got := IsIndexCleared(tt.err); got != tt.indexCleared {
+			t.Errorf("#%d: IsIndexCleared = %v, want %v", i, got, tt.indexCleared)
+		}
+		if got := IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("#%d: IsRetryable = %v, want %v", i, got, tt.retryable)
+		}
+	}
+}
+
+func TestErrorIsAs(t *testing.T) {
+	c := newTestClient(t)
+
+	_, err := c.Get("/nonexistent", false, false)
+	err = fmt.Errorf("reading config: %w", err)
+
+	if !errors.Is(err, &EtcdError{ErrorCode: ErrCodeKeyNotFound}) {
+		t.Errorf("errors.Is(%v, key not found) = false, want true", err)
+	}
+	if !errors.Is(err, EtcdError{ErrorCode: ErrCodeKeyNotFound}) {
+		t.Errorf("errors.Is(%v, EtcdError value) = false, want true", err)
+	}
+	if errors.Is(err, &EtcdError{ErrorCode: ErrCodeNodeExist}) {
+		t.Errorf("errors.Is(%v, node exist) = true, want false", err)
+	}
+
+	var etcdErr *EtcdError
+	if !errors.As(err, &etcdErr) {
+		t.Fatalf("errors.As(%v) = false, want true", err)
+	}
+	if etcdErr.Message != "Key not found" || etcdErr.Cause != "/nonexistent" {
+		t.Fatalf("error = %+v, want key not found for /nonexistent", etcdErr)
+	}
+}
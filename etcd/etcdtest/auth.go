@@ -0,0 +1,353 @@
+package etcdtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	rootUser  = "root"
+	rootRole  = "root"
+	guestRole = "guest"
+)
+
+// authStore holds the users and roles of the auth API. Once enabled, the
+// keys API checks the permissions of the user a request authenticates
+// as, or those of the guest role without credentials.
+type authStore struct {
+	mu      sync.Mutex
+	enabled bool
+	users   map[string]*authUser
+	roles   map[string]*authRole
+}
+
+type authUser struct {
+	password string
+	roles    []string
+}
+
+type authRole struct {
+	read, write []string
+}
+
+// authPermissions is the wire representation of the permissions of a role.
+type authPermissions struct {
+	KV struct {
+		Read  []string `json:"read"`
+		Write []string `json:"write"`
+	} `json:"kv"`
+}
+
+type roleJSON struct {
+	Role        string          `json:"role"`
+	Permissions authPermissions `json:"permissions"`
+}
+
+type userJSON struct {
+	User  string     `json:"user"`
+	Roles []roleJSON `json:"roles"`
+}
+
+func newAuthStore() *authStore {
+	return &authStore{
+		users: make(map[string]*authUser),
+		roles: map[string]*authRole{
+			rootRole:  {read: []string{"/*"}, write: []string{"/*"}},
+			guestRole: {read: []string{"/*"}, write: []string{"/*"}},
+		},
+	}
+}
+
+// allowed reports whether r may read, or write, key.
+func (a *authStore) allowed(r *http.Request, key string, write bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.enabled {
+		return true
+	}
+	roles := []string{guestRole}
+	if name, password, ok := r.BasicAuth(); ok {
+		u := a.users[name]
+		if u == nil || u.password != password {
+			return false
+		}
+		roles = u.roles
+	}
+
+	for _, name := range roles {
+		if name == rootRole {
+			return true
+		}
+		role := a.roles[name]
+		if role == nil {
+			continue
+		}
+		patterns := role.read
+		if write {
+			patterns = role.write
+		}
+		for _, p := range patterns {
+			if p == key || strings.HasSuffix(p, "*") && strings.HasPrefix(key, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRoot reports whether r may manage auth. It must hold a.mu.
+func (a *authStore) isRoot(r *http.Request) bool {
+	if !a.enabled {
+		return true
+	}
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	u := a.users[name]
+	return u != nil && u.password == password && containsString(u.roles, rootRole)
+}
+
+func (a *authStore) userJSON(name string) userJSON {
+	u := userJSON{User: name, Roles: []roleJSON{}}
+	for _, role := range a.users[name].roles {
+		u.Roles = append(u.Roles, a.roleJSON(role))
+	}
+	return u
+}
+
+func (a *authStore) roleJSON(name string) roleJSON {
+	r := roleJSON{Role: name}
+	r.Permissions.KV.Read = append([]string{}, a.roles[name].read...)
+	r.Permissions.KV.Write = append([]string{}, a.roles[name].write...)
+	return r
+}
+
+func (m *Member) serveAuthEnable(w http.ResponseWriter, r *http.Request) {
+	a := m.cluster.auth
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r.Method != "GET" && !a.isRoot(r) {
+		writeMessage(w, http.StatusUnauthorized, "Insufficient credentials")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, map[string]bool{"enabled": a.enabled})
+	case "PUT":
+		if a.users[rootUser] == nil {
+			writeMessage(w, http.StatusBadRequest, "auth: No root user available, please create one")
+			return
+		}
+		a.enabled = true
+		w.WriteHeader(http.StatusOK)
+	case "DELETE":
+		a.enabled = false
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *Member) serveAuthUsers(w http.ResponseWriter, r *http.Request) {
+	a := m.cluster.auth
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.isRoot(r) {
+		writeMessage(w, http.StatusUnauthorized, "Insufficient credentials")
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v2/auth/users"), "/")
+	if name == "" {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		users := []userJSON{}
+		for name := range a.users {
+			users = append(users, a.userJSON(name))
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].User < users[j].User })
+		writeJSON(w, http.StatusOK, map[string][]userJSON{"users": users})
+		return
+	}
+
+	u := a.users[name]
+	switch r.Method {
+	case "GET":
+		if u == nil {
+			writeMessage(w, http.StatusNotFound, "auth: User "+name+" does not exist.")
+			return
+		}
+		writeJSON(w, http.StatusOK, a.userJSON(name))
+	case "PUT":
+		var update struct {
+			Password string   `json:"password"`
+			Roles    []string `json:"roles"`
+			Grant    []string `json:"grant"`
+			Revoke   []string `json:"revoke"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		status := http.StatusOK
+		if u == nil {
+			if update.Password == "" {
+				writeMessage(w, http.StatusBadRequest, "auth: Password required")
+				return
+			}
+			u = &authUser{}
+			if name == rootUser {
+				u.roles = []string{rootRole}
+			}
+			update.Grant = append(update.Roles, update.Grant...)
+			status = http.StatusCreated
+		}
+		for _, role := range append(update.Grant, update.Revoke...) {
+			if a.roles[role] == nil {
+				writeMessage(w, http.StatusNotFound, "auth: Role "+role+" does not exist.")
+				return
+			}
+		}
+		if update.Password != "" {
+			u.password = update.Password
+		}
+		for _, role := range update.Grant {
+			if !containsString(u.roles, role) {
+				u.roles = append(u.roles, role)
+			}
+		}
+		u.roles = removeStrings(u.roles, update.Revoke)
+		sort.Strings(u.roles)
+		a.users[name] = u
+		writeJSON(w, status, a.userJSON(name))
+	case "DELETE":
+		switch {
+		case u == nil:
+			writeMessage(w, http.StatusNotFound, "auth: User "+name+" does not exist.")
+		case name == rootUser && a.enabled:
+			writeMessage(w, http.StatusForbidden, "auth: Cannot delete root user while auth is enabled.")
+		default:
+			delete(a.users, name)
+			w.WriteHeader(http.StatusOK)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *Member) serveAuthRoles(w http.ResponseWriter, r *http.Request) {
+	a := m.cluster.auth
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.isRoot(r) {
+		writeMessage(w, http.StatusUnauthorized, "Insufficient credentials")
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v2/auth/roles"), "/")
+	if name == "" {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		roles := []roleJSON{}
+		for name := range a.roles {
+			roles = append(roles, a.roleJSON(name))
+		}
+		sort.Slice(roles, func(i, j int) bool { return roles[i].Role < roles[j].Role })
+		writeJSON(w, http.StatusOK, map[string][]roleJSON{"roles": roles})
+		return
+	}
+
+	role := a.roles[name]
+	if role == nil && r.Method != "PUT" {
+		writeMessage(w, http.StatusNotFound, "auth: Role "+name+" does not exist.")
+		return
+	}
+	if name == rootRole && r.Method != "GET" {
+		writeMessage(w, http.StatusForbidden, "auth: Cannot modify role root: is root role.")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, a.roleJSON(name))
+	case "PUT":
+		var update struct {
+			Grant  *authPermissions `json:"grant"`
+			Revoke *authPermissions `json:"revoke"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		status := http.StatusOK
+		if role == nil {
+			role = &authRole{}
+			status = http.StatusCreated
+		} else if update.Grant == nil && update.Revoke == nil {
+			writeMessage(w, http.StatusConflict, "auth: Role "+name+" already exists.")
+			return
+		}
+		if g := update.Grant; g != nil {
+			for _, p := range g.KV.Read {
+				if !containsString(role.read, p) {
+					role.read = append(role.read, p)
+				}
+			}
+			for _, p := range g.KV.Write {
+				if !containsString(role.write, p) {
+					role.write = append(role.write, p)
+				}
+			}
+		}
+		if rv := update.Revoke; rv != nil {
+			role.read = removeStrings(role.read, rv.KV.Read)
+			role.write = removeStrings(role.write, rv.KV.Write)
+		}
+		a.roles[name] = role
+		writeJSON(w, status, a.roleJSON(name))
+	case "DELETE":
+		delete(a.roles, name)
+		for _, u := range a.users {
+			u.roles = removeStrings(u.roles, []string{name})
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStrings returns ss without the strings in remove.
+func removeStrings(ss, remove []string) []string {
+	var kept []string
+	for _, s := range ss {
+		if !containsString(remove, s) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
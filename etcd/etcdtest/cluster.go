@@ -0,0 +1,204 @@
+// Package etcdtest provides an in-process stand-in for an etcd v2 cluster,
+// so that code using go-etcd can be tested without running etcd.
+//
+// A Cluster serves the v2 keys API, including watches and TTLs, the
+// members and auth APIs, the statistics of each member and the health
+// endpoint from one or more httptest servers sharing a single key space.
+// Membership changes made through the members API are only reported by
+// it: no member is started or stopped.
+// Faults such as redirects, errors, dropped connections and dead members
+// can be injected into each member to exercise a client's retry logic.
+//
+//	cluster := etcdtest.NewCluster(3)
+//	defer cluster.Close()
+//
+//	client := etcd.NewClient(cluster.URLs())
+package etcdtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expireInterval is how often nodes are checked for expired TTLs.
+const expireInterval = 10 * time.Millisecond
+
+// Cluster is a group of members serving one key space.
+type Cluster struct {
+	Members []*Member
+
+	store *store
+	auth  *authStore
+	stopc chan struct{}
+	donec chan struct{}
+	start time.Time
+
+	mu      sync.Mutex
+	leader  *Member
+	roster  []memberJSON
+	removed map[string]bool
+}
+
+// Member is a single member of a Cluster. Its client URL is URL, while
+// PeerURLs are only reported by the members API and never listened on.
+type Member struct {
+	ID       string
+	Name     string
+	URL      string
+	PeerURLs []string
+
+	cluster *Cluster
+	server  *httptest.Server
+	mux     http.Handler
+
+	mu       sync.Mutex
+	faults   []*injected
+	requests int
+}
+
+// NewCluster starts a cluster of the given size. It must be shut down
+// with Close.
+func NewCluster(size int) *Cluster {
+	c := &Cluster{
+		store:   newStore(),
+		auth:    newAuthStore(),
+		stopc:   make(chan struct{}),
+		donec:   make(chan struct{}),
+		start:   time.Now(),
+		removed: make(map[string]bool),
+	}
+	for i := 0; i < size; i++ {
+		name := fmt.Sprintf("node%d", i+1)
+		m := &Member{
+			ID:       memberID(name),
+			Name:     name,
+			PeerURLs: []string{fmt.Sprintf("http://127.0.0.1:%d", 7001+i)},
+			cluster:  c,
+		}
+		m.mux = m.handler()
+		m.server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+		m.URL = m.server.URL
+		c.Members = append(c.Members, m)
+		c.roster = append(c.roster, memberJSON{
+			ID:         m.ID,
+			Name:       m.Name,
+			PeerURLs:   m.PeerURLs,
+			ClientURLs: []string{m.URL},
+		})
+	}
+	if size > 0 {
+		c.leader = c.Members[0]
+	}
+	go c.expireLoop()
+	return c
+}
+
+// URLs returns the client URLs of all members.
+func (c *Cluster) URLs() []string {
+	urls := make([]string, len(c.Members))
+	for i, m := range c.Members {
+		urls[i] = m.URL
+	}
+	return urls
+}
+
+// Index returns the index of the latest change to the key space.
+func (c *Cluster) Index() uint64 {
+	return c.store.currentIndex()
+}
+
+// Leader returns the member reported as the leader, at first the first
+// member.
+func (c *Cluster) Leader() *Member {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leader
+}
+
+// SetLeader makes the members report m as their leader. It does not make
+// the other members redirect requests to m; inject Redirect for that.
+func (c *Cluster) SetLeader(m *Member) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leader = m
+}
+
+// Close shuts down all members, aborting pending watches.
+func (c *Cluster) Close() {
+	close(c.stopc)
+	<-c.donec
+	for _, m := range c.Members {
+		m.server.CloseClientConnections()
+		m.server.Close()
+	}
+}
+
+func (c *Cluster) expireLoop() {
+	defer close(c.donec)
+
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			c.store.expire(now)
+		case <-c.stopc:
+			return
+		}
+	}
+}
+
+func (m *Member) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/keys", m.serveKeys)
+	mux.HandleFunc("/v2/keys/", m.serveKeys)
+	mux.HandleFunc("/v2/members", m.serveMembers)
+	mux.HandleFunc("/v2/members/", m.serveMember)
+	mux.HandleFunc("/v2/machines", m.serveMachines)
+	mux.HandleFunc("/v2/auth/enable", m.serveAuthEnable)
+	mux.HandleFunc("/v2/auth/users", m.serveAuthUsers)
+	mux.HandleFunc("/v2/auth/users/", m.serveAuthUsers)
+	mux.HandleFunc("/v2/auth/roles", m.serveAuthRoles)
+	mux.HandleFunc("/v2/auth/roles/", m.serveAuthRoles)
+	mux.HandleFunc("/version", m.serveVersion)
+	mux.HandleFunc("/health", m.serveHealth)
+	mux.HandleFunc("/v2/stats/self", m.serveSelfStats)
+	mux.HandleFunc("/v2/stats/leader", m.serveLeaderStats)
+	mux.HandleFunc("/v2/stats/store", m.serveStoreStats)
+	return mux
+}
+
+func (m *Member) serveMachines(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(strings.Join(m.cluster.URLs(), ", ")))
+}
+
+func (m *Member) serveVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"etcdserver":  "2.3.7",
+		"etcdcluster": "2.3.0",
+	})
+}
+
+func (m *Member) serveHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"health": "true"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// memberID derives a stable member ID from the member's name.
+func memberID(name string) string {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%x", h.Sum64())
+}
@@ -0,0 +1,72 @@
+package etcdtest
+
+import "net/http"
+
+// Error codes of the etcd v2 API, as sent in the errorCode field.
+const (
+	ecodeKeyNotFound  = 100
+	ecodeTestFailed   = 101
+	ecodeNotFile      = 102
+	ecodeNotDir       = 104
+	ecodeNodeExist    = 105
+	ecodeRootROnly    = 107
+	ecodeDirNotEmpty  = 108
+	ecodeTTLNaN       = 202
+	ecodeIndexNaN     = 203
+	ecodeInvalidField = 209
+
+	ecodeRefreshValue       = 211
+	ecodeRefreshTTLRequired = 212
+
+	ecodeWatcherCleared    = 400
+	ecodeEventIndexCleared = 401
+)
+
+var errorMessages = map[int]string{
+	ecodeKeyNotFound:  "Key not found",
+	ecodeTestFailed:   "Compare failed",
+	ecodeNotFile:      "Not a file",
+	ecodeNotDir:       "Not a directory",
+	ecodeNodeExist:    "Key already exists",
+	ecodeRootROnly:    "Root is read only",
+	ecodeDirNotEmpty:  "Directory not empty",
+	ecodeTTLNaN:       "The given TTL in POST form is not a number",
+	ecodeIndexNaN:     "The given index in POST form is not a number",
+	ecodeInvalidField: "Invalid field",
+
+	ecodeRefreshValue:       "Value provided on refresh",
+	ecodeRefreshTTLRequired: "A TTL must be provided on refresh",
+
+	ecodeWatcherCleared:    "watcher is cleared due to etcd recovery",
+	ecodeEventIndexCleared: "The event in requested index is outdated and cleared",
+}
+
+// etcdError is the body of a failed keys API request.
+type etcdError struct {
+	Code    int    `json:"errorCode"`
+	Message string `json:"message"`
+	Cause   string `json:"cause,omitempty"`
+	Index   uint64 `json:"index"`
+}
+
+func newError(code int, cause string, index uint64) *etcdError {
+	return &etcdError{
+		Code:    code,
+		Message: errorMessages[code],
+		Cause:   cause,
+		Index:   index,
+	}
+}
+
+// status returns the HTTP status etcd answers the error with.
+func (e *etcdError) status() int {
+	switch e.Code {
+	case ecodeKeyNotFound:
+		return http.StatusNotFound
+	case ecodeNotFile, ecodeNotDir, ecodeRootROnly, ecodeDirNotEmpty:
+		return http.StatusForbidden
+	case ecodeTestFailed, ecodeNodeExist:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusBadRequest
+}
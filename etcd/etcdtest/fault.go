@@ -0,0 +1,143 @@
+package etcdtest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A Fault makes a member misbehave. It either answers the request itself
+// or passes it on to next, the member's regular handler.
+type Fault func(w http.ResponseWriter, r *http.Request, next http.Handler)
+
+// Redirect answers with a 307 to the same path on another member, like a
+// follower that does not serve the request itself.
+func Redirect(to *Member) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		http.Redirect(w, r, to.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	}
+}
+
+// Status answers with the given status code and an empty body, such as
+// the 500 a member answers with while a leader election is in progress.
+func Status(code int) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		w.WriteHeader(code)
+	}
+}
+
+// WatcherCleared answers with the error 400 etcd sends to pending watches
+// when it drops them during a leader change or a recovery.
+func WatcherCleared() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		e := newError(ecodeWatcherCleared, "", 0)
+		writeJSON(w, e.status(), e)
+	}
+}
+
+// Delay holds the request for d before serving it, unless the client
+// gives up first.
+func Delay(d time.Duration) Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		select {
+		case <-time.After(d):
+			next.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	}
+}
+
+// DropBody answers with a successful status but closes the connection
+// halfway through the body, so that reading it fails with
+// io.ErrUnexpectedEOF.
+func DropBody() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+
+		body := `{"action":"get","node":`
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+			2*len(body), body)
+		buf.Flush()
+	}
+}
+
+// Hangup closes the connection without answering, as a member that
+// crashed while handling the request would.
+func Hangup() Fault {
+	return func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		conn.Close()
+	}
+}
+
+// injected is a fault waiting in a member's queue.
+type injected struct {
+	fault Fault
+	// left is the number of requests still to be hit, or 0 for all of them.
+	left int
+}
+
+// Inject makes the next n requests to the member suffer from f, or all
+// of them if n is 0. Faults injected one after another take effect in
+// turn.
+func (m *Member) Inject(f Fault, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = append(m.faults, &injected{fault: f, left: n})
+}
+
+// Kill makes the member hang up on every request, until it is healed.
+func (m *Member) Kill() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = []*injected{{fault: Hangup()}}
+}
+
+// Heal removes all faults from the member.
+func (m *Member) Heal() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults = nil
+}
+
+// Requests returns the number of requests the member has received,
+// including those answered by a fault.
+func (m *Member) Requests() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests
+}
+
+// nextFault counts the request and returns the fault it should suffer
+// from, if any.
+func (m *Member) nextFault() Fault {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	if len(m.faults) == 0 {
+		return nil
+	}
+	in := m.faults[0]
+	if in.left > 0 {
+		if in.left--; in.left == 0 {
+			m.faults = m.faults[1:]
+		}
+	}
+	return in.fault
+}
+
+func (m *Member) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if f := m.nextFault(); f != nil {
+		f(w, r, m.mux)
+		return
+	}
+	m.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,45 @@
+package etcdtest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInject(t *testing.T) {
+	c := NewCluster(1)
+	defer c.Close()
+
+	m := c.Members[0]
+	m.Inject(Status(http.StatusInternalServerError), 2)
+	m.Inject(Status(http.StatusServiceUnavailable), 1)
+
+	want := []int{
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusNotFound,
+	}
+	for i, w := range want {
+		resp, err := http.Get(m.URL + "/v2/keys/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != w {
+			t.Errorf("#%d: status = %d, want %d", i, resp.StatusCode, w)
+		}
+	}
+
+	if m.Requests() != len(want) {
+		t.Fatalf("requests = %d, want %d", m.Requests(), len(want))
+	}
+
+	m.Kill()
+	if _, err := http.Get(m.URL + "/v2/keys/foo"); err == nil {
+		t.Fatal("a killed member should hang up")
+	}
+	m.Heal()
+	if _, err := http.Get(m.URL + "/v2/keys/foo"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,187 @@
+package etcdtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (m *Member) serveKeys(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/v2/keys")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f := &form{Values: r.Form}
+	s := m.cluster.store
+
+	write := r.Method != "GET" && r.Method != "HEAD"
+	if !m.cluster.auth.allowed(r, cleanKey(key), write) {
+		writeMessage(w, http.StatusUnauthorized, "Insufficient credentials")
+		return
+	}
+
+	var e *event
+	var err *etcdError
+	var op string // the kind of operation, as counted by the store statistics
+	switch r.Method {
+	case "GET", "HEAD":
+		recursive, sorted, wait := f.bool("recursive"), f.bool("sorted"), f.bool("wait")
+		if err = f.err; err != nil {
+			break
+		}
+		if wait {
+			waitIndex := f.uint("waitIndex", ecodeIndexNaN)
+			if err = f.err; err != nil {
+				break
+			}
+			m.serveWatch(w, r, key, recursive, waitIndex)
+			return
+		}
+		op = "gets"
+		e, err = s.get(key, recursive, sorted)
+	case "PUT", "POST":
+		opts := putOptions{
+			value:     r.FormValue("value"),
+			dir:       f.bool("dir"),
+			ttl:       time.Duration(f.uint("ttl", ecodeTTLNaN)) * time.Second,
+			prevValue: r.FormValue("prevValue"),
+			prevIndex: f.uint("prevIndex", ecodeIndexNaN),
+			refresh:   f.bool("refresh"),
+		}
+		if _, ok := r.Form["prevExist"]; ok {
+			prevExist := f.bool("prevExist")
+			opts.prevExist = &prevExist
+		}
+		if err = f.err; err != nil {
+			break
+		}
+		if r.Method == "POST" {
+			op = "create"
+			e, err = s.createInOrder(key, opts)
+		} else {
+			op = putOp(opts)
+			e, err = s.put(key, opts)
+		}
+	case "DELETE":
+		opts := deleteOptions{
+			dir:       f.bool("dir"),
+			recursive: f.bool("recursive"),
+			prevValue: r.FormValue("prevValue"),
+			prevIndex: f.uint("prevIndex", ecodeIndexNaN),
+		}
+		if err = f.err; err != nil {
+			break
+		}
+		op = "delete"
+		if opts.prevValue != "" || opts.prevIndex != 0 {
+			op = "compareAndDelete"
+		}
+		e, err = s.del(key, opts)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if op != "" {
+		s.count(op, err == nil)
+	}
+	if err != nil {
+		writeKeysResponse(w, s.currentIndex(), err.status(), err)
+		return
+	}
+	status := http.StatusOK
+	if e.PrevNode == nil && (e.Action == "set" || e.Action == "create") {
+		status = http.StatusCreated
+	}
+	writeKeysResponse(w, s.currentIndex(), status, e)
+}
+
+// serveWatch answers with the first event affecting key at or after
+// waitIndex, waiting for it if needed.
+func (m *Member) serveWatch(w http.ResponseWriter, r *http.Request, key string, recursive bool, waitIndex uint64) {
+	s := m.cluster.store
+	if waitIndex == 0 {
+		waitIndex = s.currentIndex() + 1
+	}
+	s.addWatchers(1)
+	defer s.addWatchers(-1)
+
+	for {
+		e, changed, err := s.watch(key, recursive, waitIndex)
+		if err != nil {
+			writeKeysResponse(w, s.currentIndex(), err.status(), err)
+			return
+		}
+		if e != nil {
+			writeKeysResponse(w, s.currentIndex(), http.StatusOK, e)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-m.cluster.stopc:
+			return
+		}
+	}
+}
+
+// putOp tells which kind of operation a PUT request is.
+func putOp(opts putOptions) string {
+	switch {
+	case opts.prevValue != "" || opts.prevIndex != 0:
+		return "compareAndSwap"
+	case opts.prevExist == nil:
+		return "sets"
+	case *opts.prevExist:
+		return "update"
+	default:
+		return "create"
+	}
+}
+
+func writeKeysResponse(w http.ResponseWriter, index uint64, status int, v interface{}) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Etcd-Index", strconv.FormatUint(index, 10))
+	h.Set("X-Raft-Index", strconv.FormatUint(index, 10))
+	h.Set("X-Raft-Term", "1")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// form parses request parameters, remembering the first malformed one.
+type form struct {
+	url.Values
+	err *etcdError
+}
+
+func (f *form) bool(name string) bool {
+	v := f.Get(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil && f.err == nil {
+		f.err = newError(ecodeInvalidField, "invalid value for "+name, 0)
+	}
+	return b
+}
+
+func (f *form) uint(name string, code int) uint64 {
+	v := f.Get(name)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil && f.err == nil {
+		f.err = newError(code, "", 0)
+	}
+	return n
+}
@@ -0,0 +1,158 @@
+package etcdtest
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// memberJSON is the wire representation of a member.
+type memberJSON struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	PeerURLs   []string `json:"peerURLs"`
+	ClientURLs []string `json:"clientURLs"`
+}
+
+func (m *Member) serveMembers(w http.ResponseWriter, r *http.Request) {
+	c := m.cluster
+	switch r.Method {
+	case "GET":
+		c.mu.Lock()
+		members := append([]memberJSON{}, c.roster...)
+		c.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, struct {
+			Members []memberJSON `json:"members"`
+		}{members})
+	case "POST":
+		peerURLs, ok := readPeerURLs(w, r)
+		if !ok {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.peerURLsInUse(peerURLs, "") {
+			writeMessage(w, http.StatusConflict, "etcdserver: peerURL exists")
+			return
+		}
+		added := memberJSON{
+			ID:         memberID(strings.Join(peerURLs, ",")),
+			PeerURLs:   peerURLs,
+			ClientURLs: []string{},
+		}
+		c.roster = append(c.roster, added)
+		writeJSON(w, http.StatusCreated, added)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *Member) serveMember(w http.ResponseWriter, r *http.Request) {
+	c := m.cluster
+	id := strings.TrimPrefix(r.URL.Path, "/v2/members/")
+
+	var peerURLs []string
+	switch r.Method {
+	case "DELETE":
+	case "PUT":
+		var ok bool
+		if peerURLs, ok = readPeerURLs(w, r); !ok {
+			return
+		}
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.rosterIndex(id)
+	switch {
+	case i < 0 && c.removed[id]:
+		writeMessage(w, http.StatusGone, "Member permanently removed: "+id)
+		return
+	case i < 0:
+		writeMessage(w, http.StatusNotFound, "No such member: "+id)
+		return
+	}
+
+	if r.Method == "DELETE" {
+		c.roster = append(c.roster[:i:i], c.roster[i+1:]...)
+		c.removed[id] = true
+	} else {
+		if c.peerURLsInUse(peerURLs, id) {
+			writeMessage(w, http.StatusConflict, "etcdserver: peerURL exists")
+			return
+		}
+		c.roster[i].PeerURLs = peerURLs
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Cluster) rosterIndex(id string) int {
+	for i, member := range c.roster {
+		if member.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// peerURLsInUse reports whether a member other than the one with the
+// given ID listens on one of peerURLs.
+func (c *Cluster) peerURLsInUse(peerURLs []string, id string) bool {
+	for _, member := range c.roster {
+		if member.ID == id {
+			continue
+		}
+		for _, u := range member.PeerURLs {
+			for _, p := range peerURLs {
+				if u == p {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// readPeerURLs reads the peer URLs of a member from the JSON body of r,
+// answering the request itself if they are missing or malformed.
+func readPeerURLs(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" {
+		writeMessage(w, http.StatusUnsupportedMediaType, "Bad Content-Type "+r.Header.Get("Content-Type")+", accept application/json")
+		return nil, false
+	}
+
+	var body struct {
+		PeerURLs []string `json:"peerURLs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeMessage(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if len(body.PeerURLs) == 0 {
+		writeMessage(w, http.StatusBadRequest, "peerURLs is empty")
+		return nil, false
+	}
+	for _, p := range body.PeerURLs {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeMessage(w, http.StatusBadRequest, "invalid peer URL "+p)
+			return nil, false
+		}
+	}
+	return body.PeerURLs, true
+}
+
+// writeMessage answers like the APIs outside of the keys API do.
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,109 @@
+package etcdtest
+
+import (
+	"net/http"
+	"time"
+)
+
+// storeStatsNames are the counters reported by /v2/stats/store.
+var storeStatsNames = []string{
+	"getsSuccess", "getsFail",
+	"setsSuccess", "setsFail",
+	"deleteSuccess", "deleteFail",
+	"updateSuccess", "updateFail",
+	"createSuccess", "createFail",
+	"compareAndSwapSuccess", "compareAndSwapFail",
+	"compareAndDeleteSuccess", "compareAndDeleteFail",
+	"expireCount", "watchers",
+}
+
+// count counts a successful or failed operation of the given kind, as
+// named by the store statistics.
+func (s *store) count(op string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		s.stats[op+"Success"]++
+	} else {
+		s.stats[op+"Fail"]++
+	}
+}
+
+// addWatchers changes the number of pending watches by delta.
+func (s *store) addWatchers(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats["watchers"] = uint64(int(s.stats["watchers"]) + delta)
+}
+
+func (s *store) statistics() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]uint64, len(storeStatsNames))
+	for _, name := range storeStatsNames {
+		stats[name] = s.stats[name]
+	}
+	return stats
+}
+
+func (m *Member) serveSelfStats(w http.ResponseWriter, r *http.Request) {
+	leader := m.cluster.Leader()
+	state := "StateFollower"
+	if leader == m {
+		state = "StateLeader"
+	}
+	start := m.cluster.start.Format(time.RFC3339Nano)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":      m.Name,
+		"id":        m.ID,
+		"state":     state,
+		"startTime": start,
+		"leaderInfo": map[string]string{
+			"leader":    leader.ID,
+			"uptime":    time.Since(m.cluster.start).String(),
+			"startTime": start,
+		},
+		"recvAppendRequestCnt": 0,
+		"sendAppendRequestCnt": 0,
+	})
+}
+
+// serveLeaderStats reports every follower as answering in a millisecond.
+// Like etcd, only the leader answers.
+func (m *Member) serveLeaderStats(w http.ResponseWriter, r *http.Request) {
+	if m.cluster.Leader() != m {
+		writeMessage(w, http.StatusForbidden, "not current leader")
+		return
+	}
+
+	followers := make(map[string]interface{})
+	for _, peer := range m.cluster.Members {
+		if peer == m {
+			continue
+		}
+		followers[peer.ID] = map[string]interface{}{
+			"latency": map[string]float64{
+				"current":           1,
+				"average":           1,
+				"averageSquare":     1,
+				"standardDeviation": 0,
+				"minimum":           1,
+				"maximum":           1,
+			},
+			"counts": map[string]uint64{
+				"fail":    0,
+				"success": m.cluster.Index(),
+			},
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"leader":    m.ID,
+		"followers": followers,
+	})
+}
+
+func (m *Member) serveStoreStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.cluster.store.statistics())
+}
@@ -0,0 +1,475 @@
+package etcdtest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historySize is the number of events kept for watchers, as in etcd.
+const historySize = 1000
+
+// node is a file or directory in the key space.
+type node struct {
+	key           string
+	value         string
+	dir           bool
+	expiration    *time.Time
+	createdIndex  uint64
+	modifiedIndex uint64
+	parent        *node
+	children      map[string]*node
+}
+
+// event is a change to the key space, as reported to watchers.
+type event struct {
+	Action   string    `json:"action"`
+	Node     *jsonNode `json:"node"`
+	PrevNode *jsonNode `json:"prevNode,omitempty"`
+}
+
+func (e *event) index() uint64 { return e.Node.ModifiedIndex }
+
+// jsonNode is the wire representation of a node.
+type jsonNode struct {
+	Key           string      `json:"key,omitempty"`
+	Value         *string     `json:"value,omitempty"`
+	Dir           bool        `json:"dir,omitempty"`
+	Expiration    *time.Time  `json:"expiration,omitempty"`
+	TTL           int64       `json:"ttl,omitempty"`
+	Nodes         []*jsonNode `json:"nodes,omitempty"`
+	ModifiedIndex uint64      `json:"modifiedIndex,omitempty"`
+	CreatedIndex  uint64      `json:"createdIndex,omitempty"`
+}
+
+// store is the key space shared by all members of a Cluster.
+type store struct {
+	mu         sync.Mutex
+	root       *node
+	index      uint64
+	history    []*event
+	startIndex uint64
+	// changed is closed and replaced whenever an event is recorded.
+	changed chan struct{}
+	// stats are the counters reported by /v2/stats/store.
+	stats map[string]uint64
+}
+
+func newStore() *store {
+	return &store{
+		root:    &node{key: "/", dir: true, children: make(map[string]*node)},
+		changed: make(chan struct{}),
+		stats:   make(map[string]uint64),
+	}
+}
+
+// putOptions are the parameters of a PUT or POST request.
+type putOptions struct {
+	value     string
+	dir       bool
+	ttl       time.Duration
+	prevExist *bool
+	prevValue string
+	prevIndex uint64
+	// refresh renews the TTL of an existing key without telling watchers.
+	refresh bool
+}
+
+// deleteOptions are the parameters of a DELETE request.
+type deleteOptions struct {
+	dir       bool
+	recursive bool
+	prevValue string
+	prevIndex uint64
+}
+
+func cleanKey(key string) string {
+	return path.Clean("/" + key)
+}
+
+// lookup returns the node at key, or nil if there is none.
+func (s *store) lookup(key string) *node {
+	n := s.root
+	if key == "/" {
+		return n
+	}
+	for _, name := range strings.Split(key[1:], "/") {
+		if !n.dir {
+			return nil
+		}
+		if n = n.children[name]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// mkdirs returns the parent directory of key, creating missing
+// directories along the way at the given index.
+func (s *store) mkdirs(key string, index uint64) (*node, *etcdError) {
+	n := s.root
+	dir := path.Dir(key)
+	if dir == "/" {
+		return n, nil
+	}
+	for _, name := range strings.Split(dir[1:], "/") {
+		child := n.children[name]
+		if child == nil {
+			child = &node{
+				key:           path.Join(n.key, name),
+				dir:           true,
+				createdIndex:  index,
+				modifiedIndex: index,
+				parent:        n,
+				children:      make(map[string]*node),
+			}
+			n.children[name] = child
+		} else if !child.dir {
+			return nil, newError(ecodeNotDir, child.key, s.index)
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// get returns the node at key. Directories list their children, and
+// the children's children as well when recursive is set.
+func (s *store) get(key string, recursive, sorted bool) (*event, *etcdError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key = cleanKey(key)
+	n := s.lookup(key)
+	if n == nil {
+		return nil, newError(ecodeKeyNotFound, key, s.index)
+	}
+	jn := n.toJSON(time.Now(), true, recursive, sorted)
+	if n == s.root {
+		jn.Key = ""
+	}
+	return &event{Action: "get", Node: jn}, nil
+}
+
+// put implements set, create, update and compareAndSwap.
+func (s *store) put(key string, opts putOptions) (*event, *etcdError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.putLocked(cleanKey(key), opts)
+}
+
+// createInOrder creates a node under dir whose name sorts after the
+// names of all the nodes created before it.
+func (s *store) createInOrder(dir string, opts putOptions) (*event, *etcdError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := false
+	opts.prevExist = &created
+	return s.putLocked(path.Join(cleanKey(dir), fmt.Sprintf("%020d", s.index+1)), opts)
+}
+
+func (s *store) putLocked(key string, opts putOptions) (*event, *etcdError) {
+	if key == "/" {
+		return nil, newError(ecodeRootROnly, "/", s.index)
+	}
+	now := time.Now()
+	n := s.lookup(key)
+
+	if opts.refresh {
+		switch {
+		case opts.value != "":
+			return nil, newError(ecodeRefreshValue, key, s.index)
+		case opts.ttl == 0:
+			return nil, newError(ecodeRefreshTTLRequired, key, s.index)
+		case n == nil:
+			return nil, newError(ecodeKeyNotFound, key, s.index)
+		}
+		prev := n.toJSON(now, false, false, false)
+		n.expiration = expiration(now, opts.ttl)
+		// neither a change of the index nor an event
+		return &event{Action: "update", Node: n.toJSON(now, false, false, false), PrevNode: prev}, nil
+	}
+
+	if opts.prevValue != "" || opts.prevIndex != 0 {
+		if n == nil {
+			return nil, newError(ecodeKeyNotFound, key, s.index)
+		}
+		if n.dir {
+			return nil, newError(ecodeNotFile, key, s.index)
+		}
+		if cause, ok := n.compare(opts.prevValue, opts.prevIndex); !ok {
+			return nil, newError(ecodeTestFailed, cause, s.index)
+		}
+		prev := n.toJSON(now, false, false, false)
+		s.index++
+		n.value = opts.value
+		n.modifiedIndex = s.index
+		n.expiration = expiration(now, opts.ttl)
+		return s.record("compareAndSwap", n.toJSON(now, false, false, false), prev), nil
+	}
+
+	if opts.prevExist != nil && *opts.prevExist {
+		if n == nil {
+			return nil, newError(ecodeKeyNotFound, key, s.index)
+		}
+		if n.dir && !opts.dir {
+			return nil, newError(ecodeNotFile, key, s.index)
+		}
+		if !n.dir && opts.dir {
+			return nil, newError(ecodeNotDir, key, s.index)
+		}
+		prev := n.toJSON(now, false, false, false)
+		s.index++
+		if !n.dir {
+			n.value = opts.value
+		}
+		n.modifiedIndex = s.index
+		n.expiration = expiration(now, opts.ttl)
+		return s.record("update", n.toJSON(now, false, false, false), prev), nil
+	}
+
+	action := "set"
+	if opts.prevExist != nil {
+		action = "create"
+		if n != nil {
+			return nil, newError(ecodeNodeExist, key, s.index)
+		}
+	}
+	if n != nil && n.dir {
+		return nil, newError(ecodeNotFile, key, s.index)
+	}
+
+	parent, err := s.mkdirs(key, s.index+1)
+	if err != nil {
+		return nil, err
+	}
+	var prev *jsonNode
+	if n != nil {
+		prev = n.toJSON(now, false, false, false)
+	}
+	s.index++
+	n = &node{
+		key:           key,
+		dir:           opts.dir,
+		expiration:    expiration(now, opts.ttl),
+		createdIndex:  s.index,
+		modifiedIndex: s.index,
+		parent:        parent,
+	}
+	if opts.dir {
+		n.children = make(map[string]*node)
+	} else {
+		n.value = opts.value
+	}
+	parent.children[path.Base(key)] = n
+	return s.record(action, n.toJSON(now, false, false, false), prev), nil
+}
+
+// del implements delete and compareAndDelete.
+func (s *store) del(key string, opts deleteOptions) (*event, *etcdError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key = cleanKey(key)
+	if key == "/" {
+		return nil, newError(ecodeRootROnly, "/", s.index)
+	}
+	now := time.Now()
+	n := s.lookup(key)
+	if n == nil {
+		return nil, newError(ecodeKeyNotFound, key, s.index)
+	}
+
+	action := "delete"
+	if opts.prevValue != "" || opts.prevIndex != 0 {
+		if n.dir {
+			return nil, newError(ecodeNotFile, key, s.index)
+		}
+		if cause, ok := n.compare(opts.prevValue, opts.prevIndex); !ok {
+			return nil, newError(ecodeTestFailed, cause, s.index)
+		}
+		action = "compareAndDelete"
+	} else if n.dir {
+		if !opts.dir && !opts.recursive {
+			return nil, newError(ecodeNotFile, key, s.index)
+		}
+		if !opts.recursive && len(n.children) > 0 {
+			return nil, newError(ecodeDirNotEmpty, key, s.index)
+		}
+	}
+
+	prev := n.toJSON(now, false, false, false)
+	s.index++
+	delete(n.parent.children, path.Base(key))
+	return s.record(action, tombstone(n, s.index), prev), nil
+}
+
+// expire removes every node whose TTL has run out.
+func (s *store) expire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*node
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.expiration != nil && !n.expiration.After(now) {
+			expired = append(expired, n)
+			return
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(s.root)
+	sort.Sort(byCreatedIndex(expired))
+
+	s.stats["expireCount"] += uint64(len(expired))
+	for _, n := range expired {
+		prev := n.toJSON(now, false, false, false)
+		s.index++
+		delete(n.parent.children, path.Base(n.key))
+		s.record("expire", tombstone(n, s.index), prev)
+	}
+}
+
+// record appends an event to the history and wakes up all watchers.
+// The caller must hold s.mu.
+func (s *store) record(action string, n, prev *jsonNode) *event {
+	e := &event{Action: action, Node: n, PrevNode: prev}
+	s.history = append(s.history, e)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	s.startIndex = s.history[0].index()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return e
+}
+
+// watch returns the first event at or after sinceIndex that affects key.
+// If there is none yet it returns nil and a channel that is closed once
+// the next event is recorded.
+func (s *store) watch(key string, recursive bool, sinceIndex uint64) (*event, <-chan struct{}, *etcdError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key = cleanKey(key)
+	if sinceIndex < s.startIndex {
+		cause := fmt.Sprintf("the requested history has been cleared [%v/%v]", s.startIndex, sinceIndex)
+		return nil, nil, newError(ecodeEventIndexCleared, cause, s.index)
+	}
+	for _, e := range s.history {
+		if e.index() >= sinceIndex && matches(e, key, recursive) {
+			return e, nil, nil
+		}
+	}
+	return nil, s.changed, nil
+}
+
+// currentIndex returns the index of the latest change.
+func (s *store) currentIndex() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index
+}
+
+// matches reports whether a watcher on key is interested in e.
+func matches(e *event, key string, recursive bool) bool {
+	k := e.Node.Key
+	switch {
+	case k == key:
+		return true
+	case recursive && (key == "/" || strings.HasPrefix(k, key+"/")):
+		// changes to hidden nodes are not reported to watchers above them
+		return !strings.Contains(strings.TrimPrefix(k, strings.TrimSuffix(key, "/")), "/_")
+	case e.Node.Dir && (e.Action == "delete" || e.Action == "expire"):
+		// the watched key went away along with one of its parents
+		return strings.HasPrefix(key, k+"/")
+	}
+	return false
+}
+
+// compare checks the node against the conditions of a compareAndSwap
+// or compareAndDelete, returning the cause of a failure.
+func (n *node) compare(prevValue string, prevIndex uint64) (string, bool) {
+	valueOK := prevValue == "" || prevValue == n.value
+	indexOK := prevIndex == 0 || prevIndex == n.modifiedIndex
+	switch {
+	case !valueOK && !indexOK:
+		return fmt.Sprintf("[%v != %v] [%v != %v]", prevValue, n.value, prevIndex, n.modifiedIndex), false
+	case !valueOK:
+		return fmt.Sprintf("[%v != %v]", prevValue, n.value), false
+	case !indexOK:
+		return fmt.Sprintf("[%v != %v]", prevIndex, n.modifiedIndex), false
+	}
+	return "", true
+}
+
+// toJSON converts the node for the wire. Children are listed when list
+// is set, and all of their descendants when recursive is set too.
+func (n *node) toJSON(now time.Time, list, recursive, sorted bool) *jsonNode {
+	jn := &jsonNode{
+		Key:           n.key,
+		Dir:           n.dir,
+		ModifiedIndex: n.modifiedIndex,
+		CreatedIndex:  n.createdIndex,
+	}
+	if !n.dir {
+		value := n.value
+		jn.Value = &value
+	}
+	if n.expiration != nil {
+		exp := n.expiration.UTC()
+		jn.Expiration = &exp
+		left := n.expiration.Sub(now)
+		jn.TTL = int64(left / time.Second)
+		if left%time.Second > 0 {
+			jn.TTL++
+		}
+	}
+	if n.dir && list {
+		for name, child := range n.children {
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			jn.Nodes = append(jn.Nodes, child.toJSON(now, recursive, recursive, sorted))
+		}
+		if sorted {
+			sort.Sort(byKey(jn.Nodes))
+		}
+	}
+	return jn
+}
+
+// tombstone describes a node removed at the given index.
+func tombstone(n *node, index uint64) *jsonNode {
+	return &jsonNode{
+		Key:           n.key,
+		Dir:           n.dir,
+		ModifiedIndex: index,
+		CreatedIndex:  n.createdIndex,
+	}
+}
+
+func expiration(now time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	t := now.Add(ttl)
+	return &t
+}
+
+type byKey []*jsonNode
+
+func (ns byKey) Len() int           { return len(ns) }
+func (ns byKey) Less(i, j int) bool { return ns[i].Key < ns[j].Key }
+func (ns byKey) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
+
+type byCreatedIndex []*node
+
+func (ns byCreatedIndex) Len() int           { return len(ns) }
+func (ns byCreatedIndex) Less(i, j int) bool { return ns[i].createdIndex < ns[j].createdIndex }
+func (ns byCreatedIndex) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
@@ -0,0 +1,203 @@
+package etcdtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestStorePut(t *testing.T) {
+	s := newStore()
+
+	tests := []struct {
+		key     string
+		opts    putOptions
+		waction string
+		wcode   int
+	}{
+		{"/foo", putOptions{value: "bar"}, "set", 0},
+		{"/foo", putOptions{value: "baz"}, "set", 0},
+		{"/foo", putOptions{value: "baz", prevExist: boolPtr(false)}, "", ecodeNodeExist},
+		{"/foo", putOptions{value: "qux", prevExist: boolPtr(true)}, "update", 0},
+		{"/nonexistent", putOptions{value: "qux", prevExist: boolPtr(true)}, "", ecodeKeyNotFound},
+		{"/foo", putOptions{value: "quux", prevValue: "qux"}, "compareAndSwap", 0},
+		{"/foo", putOptions{value: "quux", prevValue: "qux"}, "", ecodeTestFailed},
+		{"/foo", putOptions{value: "quux", prevIndex: 1}, "", ecodeTestFailed},
+		{"/foo/bar", putOptions{value: "bar"}, "", ecodeNotDir},
+		{"/dir/sub/key", putOptions{value: "bar"}, "set", 0},
+		{"/dir", putOptions{value: "bar"}, "", ecodeNotFile},
+		{"/dir", putOptions{dir: true, prevExist: boolPtr(true)}, "update", 0},
+		{"/", putOptions{value: "bar"}, "", ecodeRootROnly},
+	}
+
+	for i, tt := range tests {
+		e, err := s.put(tt.key, tt.opts)
+		if err != nil {
+			if err.Code != tt.wcode {
+				t.Errorf("#%d: error code = %d, want %d", i, err.Code, tt.wcode)
+			}
+			continue
+		}
+		if tt.wcode != 0 {
+			t.Errorf("#%d: action = %s, want error code %d", i, e.Action, tt.wcode)
+			continue
+		}
+		if e.Action != tt.waction {
+			t.Errorf("#%d: action = %s, want %s", i, e.Action, tt.waction)
+		}
+		if e.index() != s.index {
+			t.Errorf("#%d: modifiedIndex = %d, want %d", i, e.index(), s.index)
+		}
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	s := newStore()
+	s.put("/dir/key", putOptions{value: "bar"})
+	s.put("/empty", putOptions{dir: true})
+
+	tests := []struct {
+		key     string
+		opts    deleteOptions
+		waction string
+		wcode   int
+	}{
+		{"/dir", deleteOptions{}, "", ecodeNotFile},
+		{"/dir", deleteOptions{dir: true}, "", ecodeDirNotEmpty},
+		{"/dir/key", deleteOptions{prevValue: "baz"}, "", ecodeTestFailed},
+		{"/dir/key", deleteOptions{prevValue: "bar"}, "compareAndDelete", 0},
+		{"/dir/key", deleteOptions{}, "", ecodeKeyNotFound},
+		{"/empty", deleteOptions{dir: true}, "delete", 0},
+		{"/dir", deleteOptions{recursive: true}, "delete", 0},
+		{"/", deleteOptions{recursive: true}, "", ecodeRootROnly},
+	}
+
+	for i, tt := range tests {
+		e, err := s.del(tt.key, tt.opts)
+		if err != nil {
+			if err.Code != tt.wcode {
+				t.Errorf("#%d: error code = %d, want %d", i, err.Code, tt.wcode)
+			}
+			continue
+		}
+		if tt.wcode != 0 || e.Action != tt.waction {
+			t.Errorf("#%d: action = %s, want %s (error code %d)", i, e.Action, tt.waction, tt.wcode)
+		}
+	}
+}
+
+func TestStoreHidden(t *testing.T) {
+	s := newStore()
+	s.put("/dir/_hidden", putOptions{value: "bar"})
+	s.put("/dir/visible", putOptions{value: "bar"})
+
+	e, err := s.get("/dir", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Node.Nodes) != 1 || e.Node.Nodes[0].Key != "/dir/visible" {
+		t.Fatalf("nodes = %+v, want only /dir/visible", e.Node.Nodes)
+	}
+	if _, err := s.get("/dir/_hidden", false, false); err != nil {
+		t.Fatalf("hidden nodes should be readable by key: %v", err)
+	}
+
+	if e, _, _ := s.watch("/dir", true, 1); e.Node.Key != "/dir/visible" {
+		t.Fatalf("watch reported %s, want /dir/visible", e.Node.Key)
+	}
+}
+
+func TestStoreWatch(t *testing.T) {
+	s := newStore()
+	s.put("/dir/a", putOptions{value: "1"})
+	s.put("/other", putOptions{value: "1"})
+
+	e, changed, err := s.watch("/dir", true, 2)
+	if err != nil || e != nil {
+		t.Fatalf("watch = %+v, %v, want to wait", e, err)
+	}
+
+	s.put("/dir/b", putOptions{value: "2"})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("watchers were not woken up")
+	}
+	if e, _, _ = s.watch("/dir", true, 2); e == nil || e.Node.Key != "/dir/b" {
+		t.Fatalf("watch = %+v, want /dir/b", e)
+	}
+
+	// deleting a directory is reported to watchers of its children
+	s.del("/dir", deleteOptions{recursive: true})
+	if e, _, _ = s.watch("/dir/a", false, 4); e == nil || e.Action != "delete" {
+		t.Fatalf("watch = %+v, want the deletion of /dir", e)
+	}
+
+	for i := 0; i < historySize; i++ {
+		s.put("/other", putOptions{value: "1"})
+	}
+	if _, _, err = s.watch("/other", false, 1); err == nil || err.Code != ecodeEventIndexCleared {
+		t.Fatalf("err = %v, want error code %d", err, ecodeEventIndexCleared)
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	s := newStore()
+	s.put("/dir", putOptions{dir: true, ttl: time.Second})
+	s.put("/dir/key", putOptions{value: "bar"})
+
+	e, _ := s.get("/dir", false, false)
+	if e.Node.TTL != 1 || e.Node.Expiration == nil {
+		t.Fatalf("node = %+v, want a TTL of 1", e.Node)
+	}
+
+	s.expire(time.Now().Add(time.Second))
+	if _, err := s.get("/dir/key", false, false); err == nil || err.Code != ecodeKeyNotFound {
+		t.Fatalf("err = %v, want error code %d", err, ecodeKeyNotFound)
+	}
+	if e, _, _ = s.watch("/dir/key", false, 3); e == nil || e.Action != "expire" {
+		t.Fatalf("watch = %+v, want the expiration of /dir", e)
+	}
+}
+
+func TestClusterKeys(t *testing.T) {
+	c := NewCluster(2)
+	defer c.Close()
+
+	resp, err := http.PostForm(c.Members[0].URL+"/v2/keys/queue", url.Values{"value": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Etcd-Index") != "1" {
+		t.Fatalf("status = %d, index = %s, want 201 and 1", resp.StatusCode, resp.Header.Get("X-Etcd-Index"))
+	}
+
+	// members share the key space
+	resp, err = http.Get(c.Members[1].URL + "/v2/keys/queue?recursive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e event
+	json.NewDecoder(resp.Body).Decode(&e)
+	resp.Body.Close()
+	if len(e.Node.Nodes) != 1 || !strings.HasPrefix(e.Node.Nodes[0].Key, "/queue/") {
+		t.Fatalf("node = %+v, want one child of /queue", e.Node)
+	}
+
+	resp, err = http.Get(c.Members[1].URL + "/v2/keys/queue?wait=true&waitIndex=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var etcdErr etcdError
+	json.NewDecoder(resp.Body).Decode(&etcdErr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || etcdErr.Code != ecodeIndexNaN {
+		t.Fatalf("status = %d, error = %+v, want error code %d", resp.StatusCode, etcdErr, ecodeIndexNaN)
+	}
+}
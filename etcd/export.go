@@ -0,0 +1,168 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ExportRecord is a key as written by Export, one JSON object per line.
+// Directories come before the keys under them, and keys under the same
+// directory in sorted order, so that exporting the same tree twice gives
+// the same output.
+type ExportRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	Dir   bool   `json:"dir,omitempty"`
+	// TTL is the number of seconds the key had left to live when exported,
+	// or 0 if it does not expire.
+	TTL           int64  `json:"ttl,omitempty"`
+	CreatedIndex  uint64 `json:"createdIndex,omitempty"`
+	ModifiedIndex uint64 `json:"modifiedIndex,omitempty"`
+}
+
+// ImportOptions tells Import how to write the keys it reads.
+type ImportOptions struct {
+	// Overwrite replaces the value of keys that already exist. Otherwise
+	// existing keys are left as they are. Either way, keys missing from
+	// the input are never deleted.
+	Overwrite bool
+	// PreserveTTL gives imported keys the TTL they were exported with.
+	// Otherwise they do not expire.
+	PreserveTTL bool
+	// Remap, if not nil, returns the key to import a record at, given the
+	// key it was exported from, or "" to leave the record out.
+	Remap func(key string) string
+}
+
+// RemapPrefix returns a function for ImportOptions.Remap that moves the
+// keys under from to the same place under to, and leaves out other keys.
+func RemapPrefix(from, to string) func(string) string {
+	from, to = path.Clean("/"+from), path.Clean("/"+to)
+	return func(key string) string {
+		switch {
+		case key == from:
+			return to
+		case from == "/":
+			return path.Join(to, key)
+		case strings.HasPrefix(key, from+"/"):
+			return path.Join(to, key[len(from):])
+		}
+		return ""
+	}
+}
+
+// Export writes the tree under prefix to w as line-delimited JSON, one
+// ExportRecord per key, starting with prefix itself.
+func (c *Client) Export(prefix string, w io.Writer) error {
+	return c.ExportContext(context.Background(), prefix, w)
+}
+
+// ExportContext is like Export but gives up once ctx is done.
+func (c *Client) ExportContext(ctx context.Context, prefix string, w io.Writer) error {
+	resp, err := c.GetContext(ctx, prefix, true, true)
+	if err != nil {
+		return err
+	}
+	return exportNode(json.NewEncoder(w), resp.Node)
+}
+
+func exportNode(enc *json.Encoder, node *Node) error {
+	// the root of the key space is not a key of its own
+	if node.Key != "" && node.Key != "/" {
+		err := enc.Encode(ExportRecord{
+			Key:           node.Key,
+			Value:         node.Value,
+			Dir:           node.Dir,
+			TTL:           node.TTL,
+			CreatedIndex:  node.CreatedIndex,
+			ModifiedIndex: node.ModifiedIndex,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range node.Nodes {
+		if err := exportNode(enc, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import reads the records written by Export from r and writes them to
+// the cluster as told by opts. It returns the keys it wrote in the order
+// it wrote them, which are all the keys written so far if it fails.
+//
+// Directories are created with SetDir, or CreateDir unless overwriting,
+// and values with Set, or Create unless overwriting. A directory that
+// already exists is kept with its contents; only its TTL is updated when
+// overwriting with PreserveTTL.
+func (c *Client) Import(r io.Reader, opts ImportOptions) ([]string, error) {
+	return c.ImportContext(context.Background(), r, opts)
+}
+
+// ImportContext is like Import but gives up once ctx is done.
+func (c *Client) ImportContext(ctx context.Context, r io.Reader, opts ImportOptions) ([]string, error) {
+	var written []string
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var record ExportRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			return written, nil
+		} else if err != nil {
+			return written, fmt.Errorf("cannot read record %d: %w", n, err)
+		}
+
+		key := record.Key
+		if opts.Remap != nil {
+			key = opts.Remap(key)
+		}
+		if key == "" {
+			continue
+		}
+		key = path.Clean("/" + key)
+
+		ok, err := c.importRecord(ctx, key, record, opts)
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written = append(written, key)
+		}
+	}
+}
+
+// importRecord writes record at key, and reports whether it did.
+func (c *Client) importRecord(ctx context.Context, key string, record ExportRecord, opts ImportOptions) (bool, error) {
+	var ttl uint64
+	if opts.PreserveTTL && record.TTL > 0 {
+		ttl = uint64(record.TTL)
+	}
+
+	var err error
+	switch {
+	case record.Dir && opts.Overwrite:
+		_, err = c.SetDirContext(ctx, key, ttl)
+		if isErrorCode(err, ErrCodeNotFile) {
+			// already a directory
+			if ttl == 0 {
+				return false, nil
+			}
+			_, err = c.UpdateDirContext(ctx, key, ttl)
+		}
+	case record.Dir:
+		_, err = c.CreateDirContext(ctx, key, ttl)
+	case opts.Overwrite:
+		_, err = c.SetContext(ctx, key, record.Value, ttl)
+	default:
+		_, err = c.CreateContext(ctx, key, record.Value, ttl)
+	}
+	if !opts.Overwrite && IsNodeExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,126 @@
+package etcd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestExport(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("/app/b", "2", 0)
+	c.Set("/app/a/x", "1", 0)
+	c.Set("/app/session", "s", 100)
+	c.SetDir("/app/empty", 0)
+	c.Set("/other", "o", 0)
+
+	var buf bytes.Buffer
+	if err := c.Export("/app", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	var session ExportRecord
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var record ExportRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		keys = append(keys, record.Key)
+		if record.Key == "/app/session" {
+			session = record
+		}
+	}
+	want := []string{"/app", "/app/a", "/app/a/x", "/app/b", "/app/empty", "/app/session"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("exported %v, want %v", keys, want)
+	}
+	if session.Value != "s" || session.TTL <= 0 || session.TTL > 100 || session.ModifiedIndex == 0 {
+		t.Fatalf("session = %+v, want its value, TTL and index", session)
+	}
+
+	var again bytes.Buffer
+	c.Export("/app", &again)
+	if again.String() != buf.String() {
+		t.Fatalf("second export differs:\n%s\n%s", again.String(), buf.String())
+	}
+
+	if err := c.Export("/missing", &buf); !IsKeyNotFound(err) {
+		t.Fatalf("Export = %v, want key not found", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	source := etcdtest.NewCluster(1)
+	defer source.Close()
+	src := NewClient(source.URLs())
+	src.Set("/app/b", "2", 0)
+	src.Set("/app/a/x", "1", 0)
+	src.Set("/app/session", "s", 100)
+	src.SetDir("/app/empty", 0)
+
+	var dump bytes.Buffer
+	if err := src.Export("/app", &dump); err != nil {
+		t.Fatal(err)
+	}
+
+	target := etcdtest.NewCluster(1)
+	defer target.Close()
+	c := NewClient(target.URLs())
+	c.Set("/restored/b", "old", 0)
+
+	// create-only leaves existing keys alone
+	written, err := c.Import(bytes.NewReader(dump.Bytes()), ImportOptions{
+		Remap: RemapPrefix("/app", "/restored"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/restored/a", "/restored/a/x", "/restored/empty", "/restored/session"}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("written %v, want %v", written, want)
+	}
+	if resp, _ := c.Get("/restored/b", false, false); resp.Node.Value != "old" {
+		t.Fatalf("b = %q, want old", resp.Node.Value)
+	}
+	if resp, _ := c.Get("/restored/empty", false, false); !resp.Node.Dir {
+		t.Fatal("empty is not a directory")
+	}
+	if resp, _ := c.Get("/restored/session", false, false); resp.Node.TTL != 0 {
+		t.Fatalf("session TTL = %d, want none", resp.Node.TTL)
+	}
+
+	// overwriting replaces values and, if asked, restores TTLs
+	written, err = c.Import(bytes.NewReader(dump.Bytes()), ImportOptions{
+		Overwrite:   true,
+		PreserveTTL: true,
+		Remap:       RemapPrefix("/app", "/restored"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"/restored/a/x", "/restored/b", "/restored/session"}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("written %v, want %v", written, want)
+	}
+	if resp, _ := c.Get("/restored/b", false, false); resp.Node.Value != "2" {
+		t.Fatalf("b = %q, want 2", resp.Node.Value)
+	}
+	if resp, _ := c.Get("/restored/session", false, false); resp.Node.TTL <= 0 || resp.Node.TTL > 100 {
+		t.Fatalf("session TTL = %d, want the exported one", resp.Node.TTL)
+	}
+
+	if _, err := c.Import(strings.NewReader(`{"key":"/ok","value":"1"}`+"\n{"), ImportOptions{}); err == nil {
+		t.Fatal("Import of a truncated dump succeeded")
+	}
+	if resp, err := c.Get("/ok", false, false); err != nil || resp.Node.Value != "1" {
+		t.Fatalf("Get = %+v, %v, want the records before the bad one", resp, err)
+	}
+}
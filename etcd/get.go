@@ -1,5 +1,7 @@
 package etcd
 
+import "context"
+
 // Get gets the file or directory associated with the given key.
 // If the key points to a directory, files and directories under
 // it will be returned in sorted or unsorted order, depending on
@@ -8,7 +10,12 @@ package etcd
 // will not be returned.
 // If recursive is set to true, all the contents will be returned.
 func (c *Client) Get(key string, sort, recursive bool) (*Response, error) {
-	raw, err := c.RawGet(key, sort, recursive)
+	return c.GetContext(context.Background(), key, sort, recursive)
+}
+
+// GetContext is like Get but gives up once ctx is done.
+func (c *Client) GetContext(ctx context.Context, key string, sort, recursive bool) (*Response, error) {
+	raw, err := c.rawGet(ctx, key, sort, recursive)
 
 	if err != nil {
 		return nil, err
@@ -18,8 +25,12 @@ func (c *Client) Get(key string, sort, recursive bool) (*Response, error) {
 }
 
 func (c *Client) RawGet(key string, sort, recursive bool) (*RawResponse, error) {
+	return c.rawGet(context.Background(), key, sort, recursive)
+}
+
+func (c *Client) rawGet(ctx context.Context, key string, sort, recursive bool) (*RawResponse, error) {
 	var q bool
-	if c.config.Consistency == STRONG_CONSISTENCY {
+	if c.consistency() == STRONG_CONSISTENCY {
 		q = true
 	}
 	ops := Options{
@@ -28,5 +39,5 @@ func (c *Client) RawGet(key string, sort, recursive bool) (*RawResponse, error)
 		"quorum":    q,
 	}
 
-	return c.get(key, ops)
+	return c.get(ctx, key, ops)
 }
@@ -26,7 +26,7 @@ func cleanResult(result *Response) {
 }
 
 func TestGet(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("foo", true)
 	}()
@@ -50,7 +50,7 @@ func TestGet(t *testing.T) {
 }
 
 func TestGetAll(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("fooDir", true)
 	}()
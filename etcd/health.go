@@ -0,0 +1,134 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// healthProbeTimeout bounds the time a member has to answer a probe.
+const healthProbeTimeout = 3 * time.Second
+
+// MemberHealth is the health of a member as last probed.
+type MemberHealth struct {
+	// URL is the client URL of the member.
+	URL string
+	// Healthy is false if the member failed its last probe. Members that
+	// were never probed are deemed healthy.
+	Healthy bool
+	// Checked is when the member was last probed, or zero if never.
+	Checked time.Time
+	// Err is why the member failed its last probe.
+	Err error
+}
+
+// Health returns the health of every member of the cluster, as found by
+// the last call to CheckHealth.
+func (c *Client) Health() []MemberHealth {
+	return c.cluster.memberHealth()
+}
+
+// CheckHealth probes every member of the cluster at once, and returns
+// their health.
+//
+// The client stops sending requests to unhealthy members, unless none is
+// healthy, and sends them requests again once they pass a probe.
+func (c *Client) CheckHealth(ctx context.Context) []MemberHealth {
+	var wg sync.WaitGroup
+	for _, machine := range c.cluster.machines() {
+		wg.Add(1)
+		go func(machine string) {
+			defer wg.Done()
+
+			err := c.probe(ctx, machine)
+			if err != nil {
+				logger.Warningf("health: %s is unhealthy: %v", machine, err)
+			}
+			c.cluster.setHealth(MemberHealth{
+				URL:     machine,
+				Healthy: err == nil,
+				Checked: time.Now(),
+				Err:     err,
+			})
+		}(machine)
+	}
+	wg.Wait()
+
+	return c.Health()
+}
+
+// HealthCheck calls CheckHealth every interval until ctx is done, and
+// then returns ctx.Err().
+func (c *Client) HealthCheck(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckHealth(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// probe checks the /health endpoint of a member. Members too old to have
+// one only need to answer /v2/stats/self.
+func (c *Client) probe(ctx context.Context, machine string) error {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	resp, err := c.probeGet(ctx, c.createHttpPath(machine, "health"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		if resp, err = c.probeGet(ctx, c.createHttpPath(machine, path.Join(version, "stats", "self"))); err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var health struct {
+		Health string `json:"health"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.Health != "true" {
+		return fmt.Errorf("member reports health %q", health.Health)
+	}
+	return nil
+}
+
+func (c *Client) probeGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	credentials := c.credentials
+	c.mu.RUnlock()
+	if credentials != nil {
+		req.SetBasicAuth(credentials.username, credentials.password)
+	}
+
+	return c.getHTTPClient().Do(req.WithContext(ctx))
+}
@@ -0,0 +1,118 @@
+package etcd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestCheckHealth(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	dead := cluster.Members[1]
+	dead.Kill()
+
+	for _, h := range c.CheckHealth(context.Background()) {
+		if wantHealthy := h.URL != dead.URL; h.Healthy != wantHealthy || h.Checked.IsZero() {
+			t.Fatalf("health = %+v, want healthy %v", h, wantHealthy)
+		}
+	}
+
+	// unhealthy members are skipped
+	c.cluster.mu.Lock()
+	c.cluster.picked = indexOf(c.cluster.Machines, dead.URL)
+	c.cluster.mu.Unlock()
+	probes := dead.Requests()
+	for i := 0; i < 5; i++ {
+		if _, err := c.Set("foo", "bar", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dead.Requests() != probes {
+		t.Fatalf("dead member received %d requests, want none", dead.Requests()-probes)
+	}
+
+	// and admitted again once they recover
+	dead.Heal()
+	for _, h := range c.CheckHealth(context.Background()) {
+		if !h.Healthy {
+			t.Fatalf("health = %+v, want healthy", h)
+		}
+	}
+}
+
+func TestCheckHealthNoneHealthy(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	for _, m := range cluster.Members {
+		m.Kill()
+	}
+	c.CheckHealth(context.Background())
+
+	// requests still go to some member rather than nowhere
+	picked := c.cluster.pick()
+	if indexOf(cluster.URLs(), picked) < 0 {
+		t.Fatalf("picked %q, want a member", picked)
+	}
+}
+
+func TestCheckHealthStatsFallback(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/stats/self" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	// with or without a trailing slash
+	for _, machine := range []string{s.URL, s.URL + "/"} {
+		c := NewClient([]string{machine})
+		if h := c.CheckHealth(context.Background()); !h[0].Healthy {
+			t.Fatalf("health of %s = %+v, want healthy", machine, h[0])
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.HealthCheck(ctx, 10*time.Millisecond) }()
+
+	cluster.Members[0].Kill()
+	for i := 0; ; i++ {
+		h := c.Health()
+		if !h[indexOf(c.GetCluster(), cluster.Members[0].URL)].Healthy {
+			break
+		}
+		if i == 100 {
+			t.Fatal("the dead member was not found unhealthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("HealthCheck = %v, want %v", err, context.Canceled)
+	}
+}
+
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,80 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrLeaderUnknown is returned when the cluster has no leader, or when its
+// leader cannot be reached through the machines the client knows.
+var ErrLeaderUnknown = errors.New("leader of the cluster is unknown")
+
+// Leader returns the client URL of the leader of the cluster, asking the
+// cluster if it is not known yet.
+//
+// Requests that have to reach the leader, that is all requests but GET
+// requests under WEAK_CONSISTENCY, are sent to it directly rather than
+// through a redirect by a follower. The leader is learned again from
+// redirects and SyncCluster when it changes, and is no longer used once
+// it fails to answer.
+func (c *Client) Leader() (string, error) {
+	return c.LeaderContext(context.Background())
+}
+
+// LeaderContext is like Leader but gives up once ctx is done.
+func (c *Client) LeaderContext(ctx context.Context) (string, error) {
+	if leader := c.cluster.leader(); leader != "" {
+		return leader, nil
+	}
+	return c.syncLeader(ctx)
+}
+
+// syncLeader asks the cluster which member leads it, and starts sending
+// the leader its requests.
+func (c *Client) syncLeader(ctx context.Context) (string, error) {
+	stats, err := c.SelfStatsContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	var members memberCollection
+	if err := c.getJSON(ctx, "members", &members); err != nil {
+		return "", err
+	}
+
+	machines := c.cluster.machines()
+	for _, m := range members {
+		if m.ID != stats.LeaderInfo.Leader {
+			continue
+		}
+		for _, u := range m.ClientURLs {
+			if containsString(machines, u) {
+				c.cluster.setLeader(u)
+				c.saveConfig()
+				return u, nil
+			}
+		}
+	}
+	return "", ErrLeaderUnknown
+}
+
+// getJSON gets the given path under the API root and decodes the JSON
+// response into v.
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	return c.sendJSON(ctx, NewRawRequest("GET", path, nil, nil), v)
+}
+
+// sendJSON sends rr and decodes the JSON response into v.
+func (c *Client) sendJSON(ctx context.Context, rr *RawRequest, v interface{}) error {
+	raw, err := c.SendRequestContext(ctx, rr)
+	if err != nil {
+		return err
+	}
+	if raw.StatusCode != http.StatusOK {
+		errStr := fmt.Sprintf("unhandled http status [%s] with body [%s]", http.StatusText(raw.StatusCode), raw.Body)
+		return newError(ErrCodeUnhandledHTTPStatus, errStr, 0)
+	}
+	return json.Unmarshal(raw.Body, v)
+}
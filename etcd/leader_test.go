@@ -0,0 +1,153 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+// requestCounts returns the number of requests received by each member.
+func requestCounts(cluster *etcdtest.Cluster) []int {
+	counts := make([]int, len(cluster.Members))
+	for i, m := range cluster.Members {
+		counts[i] = m.Requests()
+	}
+	return counts
+}
+
+func TestLeader(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	leader := cluster.Members[2]
+	cluster.SetLeader(leader)
+
+	c := NewClient(cluster.URLs())
+	if got, err := c.Leader(); err != nil || got != leader.URL {
+		t.Fatalf("Leader = %q, %v, want %q", got, err, leader.URL)
+	}
+
+	// writes go to the leader
+	before := requestCounts(cluster)
+	for i := 0; i < 5; i++ {
+		if _, err := c.Set("foo", "bar", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := requestCounts(cluster)
+	if after[2]-before[2] != 5 || after[0] != before[0] || after[1] != before[1] {
+		t.Fatalf("requests = %v, then %v, want 5 more to the leader only", before, after)
+	}
+
+	// and so does everything under strong consistency
+	c.SetConsistency(STRONG_CONSISTENCY)
+	if _, err := c.Get("foo", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if leader.Requests() != after[2]+1 {
+		t.Fatal("strongly consistent read did not go to the leader")
+	}
+}
+
+func TestLeaderRedirect(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	follower, leader := cluster.Members[0], cluster.Members[1]
+	cluster.SetLeader(leader)
+	follower.Inject(etcdtest.Redirect(leader), 0)
+
+	c := NewClient([]string{follower.URL, leader.URL})
+	c.cluster.setLeader("")
+	c.cluster.mu.Lock()
+	c.cluster.picked = indexOf(c.cluster.Machines, follower.URL)
+	c.cluster.mu.Unlock()
+
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.cluster.leader() != leader.URL {
+		t.Fatalf("leader = %q, want %q from the redirect", c.cluster.leader(), leader.URL)
+	}
+
+	redirects := follower.Requests()
+	if _, err := c.Set("foo", "baz", 0); err != nil {
+		t.Fatal(err)
+	}
+	if follower.Requests() != redirects {
+		t.Fatal("write went through the follower again")
+	}
+}
+
+func TestLeaderRedirectNotMember(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+	other := etcdtest.NewCluster(1)
+	defer other.Close()
+
+	member, stranger := cluster.Members[0], other.Members[0]
+	member.Inject(etcdtest.Redirect(stranger), 2)
+
+	c := NewClient([]string{member.URL})
+	c.cluster.setLeader("")
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("foo", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if c.cluster.leader() != "" {
+		t.Fatalf("leader = %q, want none after redirects away from the members", c.cluster.leader())
+	}
+}
+
+func TestLeaderSyncKept(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	if !c.SyncCluster() {
+		t.Fatal("cannot sync the cluster")
+	}
+	before := requestCounts(cluster)
+	if !c.SyncCluster() {
+		t.Fatal("cannot sync the cluster")
+	}
+	after := requestCounts(cluster)
+	sent := 0
+	for i := range after {
+		sent += after[i] - before[i]
+	}
+	if sent != 1 {
+		t.Fatalf("sync sent %d requests with the leader known, want 1", sent)
+	}
+	if c.cluster.leader() != cluster.Members[0].URL {
+		t.Fatalf("leader = %q, want %q kept", c.cluster.leader(), cluster.Members[0].URL)
+	}
+}
+
+func TestLeaderFailed(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	if !c.SyncCluster() {
+		t.Fatal("cannot sync the cluster")
+	}
+	if c.cluster.leader() != cluster.Members[0].URL {
+		t.Fatalf("leader = %q, want %q after a sync", c.cluster.leader(), cluster.Members[0].URL)
+	}
+
+	cluster.Members[0].Kill()
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.cluster.leader() != "" {
+		t.Fatalf("leader = %q, want the dead leader forgotten", c.cluster.leader())
+	}
+
+	cluster.SetLeader(cluster.Members[1])
+	if got, err := c.Leader(); err != nil || got != cluster.Members[1].URL {
+		t.Fatalf("Leader = %q, %v, want %q", got, err, cluster.Members[1].URL)
+	}
+}
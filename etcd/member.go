@@ -1,7 +1,13 @@
 package etcd
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
 
+// Member is a member of the cluster, as reported by the members API.
+// Members added but not started yet have no name and no client URLs.
 type Member struct {
 	ID         string   `json:"id"`
 	Name       string   `json:"name"`
@@ -28,3 +34,108 @@ func (c *memberCollection) UnmarshalJSON(data []byte) error {
 	*c = d.Members
 	return nil
 }
+
+// memberUpdate is the body of the requests adding or updating a member.
+type memberUpdate struct {
+	PeerURLs []string `json:"peerURLs"`
+}
+
+// ListMembers returns the members of the cluster.
+func (c *Client) ListMembers() ([]Member, error) {
+	return c.ListMembersContext(context.Background())
+}
+
+// ListMembersContext is like ListMembers but gives up once ctx is done.
+func (c *Client) ListMembersContext(ctx context.Context) ([]Member, error) {
+	var members memberCollection
+	if err := c.sendAPI(ctx, NewRawRequest("GET", "members", nil, nil), &members, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember adds a member listening for its peers on peerURLs to the
+// cluster. The member must then be started to join it.
+//
+// Failures are reported as an *HTTPError, which satisfies IsConflict if
+// one of the peer URLs is already used by another member.
+func (c *Client) AddMember(peerURLs []string) (*Member, error) {
+	return c.AddMemberContext(context.Background(), peerURLs)
+}
+
+// AddMemberContext is like AddMember but gives up once ctx is done.
+func (c *Client) AddMemberContext(ctx context.Context, peerURLs []string) (*Member, error) {
+	rr, err := newJSONRequest("POST", "members", memberUpdate{peerURLs})
+	if err != nil {
+		return nil, err
+	}
+	m := new(Member)
+	if err := c.sendAPI(ctx, rr, m, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RemoveMember removes the member with the given ID from the cluster.
+// The client keeps sending requests to it until the next SyncCluster.
+//
+// Failures are reported as an *HTTPError, with status 404 if there is no
+// such member and 410 if it was removed already.
+func (c *Client) RemoveMember(id string) error {
+	return c.RemoveMemberContext(context.Background(), id)
+}
+
+// RemoveMemberContext is like RemoveMember but gives up once ctx is done.
+func (c *Client) RemoveMemberContext(ctx context.Context, id string) error {
+	return c.sendAPI(ctx, NewRawRequest("DELETE", "members/"+id, nil, nil), nil, http.StatusNoContent)
+}
+
+// UpdateMember changes the peer URLs of the member with the given ID.
+//
+// Failures are reported as an *HTTPError, with status 404 if there is no
+// such member, and which satisfies IsConflict if one of the peer URLs is
+// already used by another member.
+func (c *Client) UpdateMember(id string, peerURLs []string) error {
+	return c.UpdateMemberContext(context.Background(), id, peerURLs)
+}
+
+// UpdateMemberContext is like UpdateMember but gives up once ctx is done.
+func (c *Client) UpdateMemberContext(ctx context.Context, id string, peerURLs []string) error {
+	rr, err := newJSONRequest("PUT", "members/"+id, memberUpdate{peerURLs})
+	if err != nil {
+		return err
+	}
+	return c.sendAPI(ctx, rr, nil, http.StatusNoContent)
+}
+
+// newJSONRequest returns a request with v as its JSON body.
+func newJSONRequest(method, relativePath string, v interface{}) (*RawRequest, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	rr := NewRawRequest(method, relativePath, nil, nil)
+	rr.body = body
+	return rr, nil
+}
+
+// sendAPI sends rr to an API reporting failures through the HTTP status,
+// and decodes the JSON response into v, if not nil, when it has one of
+// the expected statuses. Other responses are returned as an *HTTPError.
+func (c *Client) sendAPI(ctx context.Context, rr *RawRequest, v interface{}, statuses ...int) error {
+	raw, err := c.SendRequestContext(ctx, rr)
+	if err != nil {
+		return err
+	}
+	expected := false
+	for _, status := range statuses {
+		expected = expected || raw.StatusCode == status
+	}
+	if !expected {
+		return newHTTPError(raw)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(raw.Body, v)
+}
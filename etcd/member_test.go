@@ -2,8 +2,12 @@ package etcd
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
 	"reflect"
 	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
 )
 
 func TestMemberCollectionUnmarshal(t *testing.T) {
@@ -69,3 +73,73 @@ func TestMemberCollectionUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestMembersAPI(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	members, err := c.ListMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 3 || members[0].ID != cluster.Members[0].ID || members[0].ClientURLs[0] != cluster.Members[0].URL {
+		t.Fatalf("members = %+v", members)
+	}
+
+	added, err := c.AddMember([]string{"http://127.0.0.1:7004"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == "" || added.Name != "" || !reflect.DeepEqual(added.PeerURLs, []string{"http://127.0.0.1:7004"}) {
+		t.Fatalf("added = %+v", added)
+	}
+	if members, _ = c.ListMembers(); len(members) != 4 {
+		t.Fatalf("got %d members, want 4", len(members))
+	}
+
+	if err := c.UpdateMember(added.ID, []string{"http://127.0.0.1:7005"}); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ = c.ListMembers(); members[3].PeerURLs[0] != "http://127.0.0.1:7005" {
+		t.Fatalf("peer URLs = %v, want updated", members[3].PeerURLs)
+	}
+
+	if err := c.RemoveMember(added.ID); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ = c.ListMembers(); len(members) != 3 {
+		t.Fatalf("got %d members, want 3", len(members))
+	}
+}
+
+func TestMembersAPIErrors(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	inUse := cluster.Members[1].PeerURLs
+
+	_, err := c.AddMember(inUse)
+	if !IsConflict(err) {
+		t.Fatalf("AddMember = %v, want a conflict", err)
+	}
+	if err := c.UpdateMember(cluster.Members[0].ID, inUse); !IsConflict(err) {
+		t.Fatalf("UpdateMember = %v, want a conflict", err)
+	}
+
+	if _, err := c.AddMember([]string{"not a url"}); !errors.Is(err, &HTTPError{StatusCode: http.StatusBadRequest}) {
+		t.Fatalf("AddMember = %v, want a bad request", err)
+	}
+
+	if err := c.RemoveMember("missing"); !errors.Is(err, &HTTPError{StatusCode: http.StatusNotFound}) {
+		t.Fatalf("RemoveMember = %v, want not found", err)
+	}
+	id := cluster.Members[1].ID
+	if err := c.RemoveMember(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveMember(id); !errors.Is(err, &HTTPError{StatusCode: http.StatusGone}) {
+		t.Fatalf("RemoveMember = %v, want gone", err)
+	}
+}
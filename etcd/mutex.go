@@ -0,0 +1,216 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors introduced by Mutex.
+var (
+	ErrMutexLocked    = errors.New("mutex is locked by someone else")
+	ErrMutexNotLocked = errors.New("mutex is not locked")
+	ErrMutexLost      = errors.New("mutex key is gone before the lock was acquired")
+)
+
+// Mutex is a lock shared by every client using the same directory.
+//
+// Contenders queue up by creating in-order keys under the directory, and
+// the lock belongs to the one with the lowest key. Each waiting contender
+// only watches the key right in front of it, so that releasing the lock
+// wakes up a single waiter. The keys are created with a TTL and refreshed
+// in the background while waiting and while holding the lock, so the lock
+// passes on if its holder dies, and a holder that failed to refresh its
+// key in time finds out through Lost.
+//
+// Like sync.Mutex, a Mutex must be unlocked before it is locked again.
+type Mutex struct {
+	client *Client
+	dir    string
+	ttl    uint64
+
+	mu   sync.Mutex
+	key  string
+	stop context.CancelFunc
+	done chan struct{}
+	lost chan struct{}
+}
+
+// NewMutex returns a mutex using the keys under dir. The keys expire after
+// ttl seconds unless refreshed; a ttl of 0 makes them permanent.
+func NewMutex(client *Client, dir string, ttl uint64) *Mutex {
+	return &Mutex{
+		client: client,
+		dir:    dir,
+		ttl:    ttl,
+	}
+}
+
+// Lock blocks until the lock is acquired or ctx is done.
+func (m *Mutex) Lock(ctx context.Context) error {
+	return m.lock(ctx, true)
+}
+
+// TryLock acquires the lock if nobody holds or waits for it, and fails
+// with ErrMutexLocked otherwise.
+func (m *Mutex) TryLock(ctx context.Context) error {
+	return m.lock(ctx, false)
+}
+
+// Unlock releases the lock.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mu.Lock()
+	key, stop, done := m.key, m.stop, m.done
+	m.key, m.stop, m.done = "", nil, nil
+	m.mu.Unlock()
+
+	if key == "" {
+		return ErrMutexNotLocked
+	}
+
+	stop()
+	<-done
+
+	_, err := m.client.DeleteContext(ctx, key, false)
+	return err
+}
+
+// Lost returns a channel that is closed when the lock acquired by the last
+// successful Lock or TryLock is lost, because its key expired before it
+// could be refreshed or was deleted by someone else. It is not closed by
+// Unlock.
+func (m *Mutex) Lost() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lost
+}
+
+// Key returns the key by which the lock is held, or "" if it is not held.
+func (m *Mutex) Key() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.key
+}
+
+func (m *Mutex) lock(ctx context.Context, wait bool) error {
+	sent := time.Now()
+	resp, err := m.client.CreateInOrderContext(ctx, m.dir, "", m.ttl)
+	if err != nil {
+		return err
+	}
+	key := resp.Node.Key
+
+	refreshCtx, stop := context.WithCancel(context.Background())
+	done, lost := make(chan struct{}), make(chan struct{})
+	go m.refresh(refreshCtx, key, sent, done, lost)
+
+	if err := m.waitTurn(ctx, key, wait); err != nil {
+		stop()
+		<-done
+
+		// Leave the queue, even though ctx may be done already.
+		if _, delErr := m.client.Delete(key, false); delErr != nil {
+			logger.Warningf("mutex: cannot delete %s: %v", key, delErr)
+		}
+		return err
+	}
+
+	m.mu.Lock()
+	m.key, m.stop, m.done, m.lost = key, stop, done, lost
+	m.mu.Unlock()
+
+	return nil
+}
+
+// waitTurn returns once key is the lowest key under the directory.
+func (m *Mutex) waitTurn(ctx context.Context, key string, wait bool) error {
+	for {
+		resp, err := m.client.GetContext(ctx, m.dir, true, false)
+		if err != nil {
+			return err
+		}
+
+		prev, found := "", false
+		for _, node := range resp.Node.Nodes {
+			if node.Dir {
+				continue
+			}
+			if node.Key == key {
+				found = true
+				break
+			}
+			prev = node.Key
+		}
+
+		switch {
+		case !found:
+			return ErrMutexLost
+		case prev == "":
+			return nil
+		case !wait:
+			return ErrMutexLocked
+		}
+
+		if err := m.client.waitDelete(ctx, prev, resp.EtcdIndex+1); err != nil {
+			return err
+		}
+	}
+}
+
+// refresh renews the TTL of key until ctx is cancelled, and closes lost
+// if key is deleted, or could not be refreshed, for whatever reason,
+// before it expired. refreshed is when key was created.
+//
+// The key is refreshed without changing it, so that the contender waiting
+// for its deletion is not woken up for nothing.
+func (m *Mutex) refresh(ctx context.Context, key string, refreshed time.Time, done, lost chan struct{}) {
+	defer close(done)
+
+	if m.ttl == 0 {
+		return
+	}
+
+	ttl := time.Duration(m.ttl) * time.Second
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	expired := time.NewTimer(ttl)
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// a refresh arriving after the key expired is of no use
+			expiry := refreshed.Add(ttl)
+			refreshCtx, cancel := context.WithDeadline(ctx, expiry)
+			sent := time.Now()
+			_, err := m.client.refreshTTL(refreshCtx, key, m.ttl)
+			cancel()
+			if err == nil {
+				refreshed = sent
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.Warningf("mutex: cannot refresh %s: %v", key, err)
+			if IsKeyNotFound(err) || !time.Now().Before(expiry) {
+				close(lost)
+				return
+			}
+		case <-expired.C:
+			if left := time.Until(refreshed.Add(ttl)); left > 0 {
+				expired.Reset(left)
+				continue
+			}
+			logger.Warningf("mutex: %s expired before it could be refreshed", key)
+			close(lost)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestMutex(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx := context.Background()
+	m1 := NewMutex(NewClient(cluster.URLs()), "/lock", 0)
+	m2 := NewMutex(NewClient(cluster.URLs()), "/lock", 0)
+
+	if err := m1.Unlock(ctx); err != ErrMutexNotLocked {
+		t.Fatalf("Unlock = %v, want %v", err, ErrMutexNotLocked)
+	}
+	if err := m1.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m2.TryLock(ctx); err != ErrMutexLocked {
+		t.Fatalf("TryLock = %v, want %v", err, ErrMutexLocked)
+	}
+
+	locked := make(chan error)
+	go func() { locked <- m2.Lock(ctx) }()
+
+	select {
+	case err := <-locked:
+		t.Fatalf("Lock returned %v while the mutex was held", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := m1.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock did not return after the mutex was released")
+	}
+
+	// failed attempts leave no key behind
+	resp, err := m1.client.Get("/lock", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Node.Nodes) != 1 || resp.Node.Nodes[0].Key != m2.Key() {
+		t.Fatalf("nodes = %v, want only %s", resp.Node.Nodes, m2.Key())
+	}
+}
+
+func TestMutexOrder(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx := context.Background()
+	holder := NewMutex(NewClient(cluster.URLs()), "/lock", 0)
+	if err := holder.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 3
+	order := make(chan int, n)
+	for i := 0; i < n; i++ {
+		m := NewMutex(NewClient(cluster.URLs()), "/lock", 0)
+		go func(i int) {
+			if err := m.Lock(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			m.Unlock(ctx)
+		}(i)
+		// queue up the waiters one after another
+		waitForNodes(t, holder.client, "/lock", i+2)
+	}
+
+	if err := holder.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case got := <-order:
+			if got != i {
+				t.Fatalf("waiter %d got the lock in turn %d", got, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("waiter %d did not get the lock", i)
+		}
+	}
+}
+
+func TestMutexRefresh(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx := context.Background()
+	m := NewMutex(c, "/lock", 1)
+	if err := m.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := c.Get(m.Key(), false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	after, err := c.Get(m.Key(), false, false)
+	if err != nil {
+		t.Fatalf("lock expired while held: %v", err)
+	}
+	// refreshing does not change the key, which would wake up waiters
+	if after.Node.ModifiedIndex != before.Node.ModifiedIndex {
+		t.Fatalf("modified index = %d, want %d", after.Node.ModifiedIndex, before.Node.ModifiedIndex)
+	}
+
+	if err := m.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutexLost(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx := context.Background()
+	m := NewMutex(c, "/lock", 1)
+	if err := m.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Delete(m.Key(), false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-m.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("loss of the lock was not reported")
+	}
+}
+
+func TestMutexLostUnreachable(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	m := NewMutex(NewClient(cluster.URLs()), "/lock", 1)
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a holder cut off from the cluster is told once the key expires
+	cluster.Members[0].Kill()
+	select {
+	case <-m.Lost():
+	case <-time.After(3 * time.Second):
+		t.Fatal("loss of the lock was not reported")
+	}
+}
+
+func TestMutexLockTimeout(t *testing.T) {
+	c := newTestClient(t)
+
+	holder := NewMutex(c, "/lock", 0)
+	if err := holder.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := NewMutex(c, "/lock", 0).Lock(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Lock = %v, want %v", err, context.DeadlineExceeded)
+	}
+	waitForNodes(t, c, "/lock", 1)
+}
+
+// waitForNodes waits until dir has exactly n children.
+func waitForNodes(t *testing.T, c *Client, dir string, n int) {
+	t.Helper()
+
+	var nodes Nodes
+	for i := 0; i < 100; i++ {
+		resp, err := c.Get(dir, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes = resp.Node.Nodes; len(nodes) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s has %d nodes, want %d", dir, len(nodes), n)
+}
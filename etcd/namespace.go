@@ -0,0 +1,233 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+// Namespace is a view of a client in which every key is under a prefix,
+// so that code written against the root of the key space can work in a
+// subtree of it instead.
+//
+// Keys are given and returned relative to the prefix: the prefix is added
+// to the keys of requests, and removed from the keys of the nodes in
+// responses and from the causes of errors. Keys cannot reach out of the
+// prefix; ".." at the root of the namespace is the root itself.
+type Namespace struct {
+	client *Client
+	prefix string
+}
+
+// Namespace returns a view of the client in which every key is under
+// prefix.
+func (c *Client) Namespace(prefix string) *Namespace {
+	return &Namespace{client: c, prefix: path.Clean("/" + prefix)}
+}
+
+// Namespace returns a view of the namespace in which every key is under
+// prefix, itself under the prefix of n.
+func (n *Namespace) Namespace(prefix string) *Namespace {
+	return &Namespace{client: n.client, prefix: n.key(prefix)}
+}
+
+// Prefix returns the prefix of every key in the namespace.
+func (n *Namespace) Prefix() string {
+	return n.prefix
+}
+
+// Client returns the client the namespace sends its requests with.
+func (n *Namespace) Client() *Client {
+	return n.client
+}
+
+// Get is like Client.Get within the namespace.
+func (n *Namespace) Get(key string, sort, recursive bool) (*Response, error) {
+	return n.GetContext(context.Background(), key, sort, recursive)
+}
+
+// GetContext is like Get but gives up once ctx is done.
+func (n *Namespace) GetContext(ctx context.Context, key string, sort, recursive bool) (*Response, error) {
+	return n.strip(n.client.GetContext(ctx, n.key(key), sort, recursive))
+}
+
+// Set is like Client.Set within the namespace.
+func (n *Namespace) Set(key string, value string, ttl uint64) (*Response, error) {
+	return n.SetContext(context.Background(), key, value, ttl)
+}
+
+// SetContext is like Set but gives up once ctx is done.
+func (n *Namespace) SetContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	return n.strip(n.client.SetContext(ctx, n.key(key), value, ttl))
+}
+
+// SetDir is like Client.SetDir within the namespace.
+func (n *Namespace) SetDir(key string, ttl uint64) (*Response, error) {
+	return n.SetDirContext(context.Background(), key, ttl)
+}
+
+// SetDirContext is like SetDir but gives up once ctx is done.
+func (n *Namespace) SetDirContext(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	return n.strip(n.client.SetDirContext(ctx, n.key(key), ttl))
+}
+
+// Create is like Client.Create within the namespace.
+func (n *Namespace) Create(key string, value string, ttl uint64) (*Response, error) {
+	return n.CreateContext(context.Background(), key, value, ttl)
+}
+
+// CreateContext is like Create but gives up once ctx is done.
+func (n *Namespace) CreateContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	return n.strip(n.client.CreateContext(ctx, n.key(key), value, ttl))
+}
+
+// CreateInOrder is like Client.CreateInOrder within the namespace.
+func (n *Namespace) CreateInOrder(dir string, value string, ttl uint64) (*Response, error) {
+	return n.CreateInOrderContext(context.Background(), dir, value, ttl)
+}
+
+// CreateInOrderContext is like CreateInOrder but gives up once ctx is done.
+func (n *Namespace) CreateInOrderContext(ctx context.Context, dir string, value string, ttl uint64) (*Response, error) {
+	return n.strip(n.client.CreateInOrderContext(ctx, n.key(dir), value, ttl))
+}
+
+// Update is like Client.Update within the namespace.
+func (n *Namespace) Update(key string, value string, ttl uint64) (*Response, error) {
+	return n.UpdateContext(context.Background(), key, value, ttl)
+}
+
+// UpdateContext is like Update but gives up once ctx is done.
+func (n *Namespace) UpdateContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	return n.strip(n.client.UpdateContext(ctx, n.key(key), value, ttl))
+}
+
+// CompareAndSwap is like Client.CompareAndSwap within the namespace.
+func (n *Namespace) CompareAndSwap(key string, value string, ttl uint64,
+	prevValue string, prevIndex uint64) (*Response, error) {
+	return n.CompareAndSwapContext(context.Background(), key, value, ttl, prevValue, prevIndex)
+}
+
+// CompareAndSwapContext is like CompareAndSwap but gives up once ctx is
+// done.
+func (n *Namespace) CompareAndSwapContext(ctx context.Context, key string, value string, ttl uint64,
+	prevValue string, prevIndex uint64) (*Response, error) {
+	return n.strip(n.client.CompareAndSwapContext(ctx, n.key(key), value, ttl, prevValue, prevIndex))
+}
+
+// Delete is like Client.Delete within the namespace.
+func (n *Namespace) Delete(key string, recursive bool) (*Response, error) {
+	return n.DeleteContext(context.Background(), key, recursive)
+}
+
+// DeleteContext is like Delete but gives up once ctx is done.
+func (n *Namespace) DeleteContext(ctx context.Context, key string, recursive bool) (*Response, error) {
+	return n.strip(n.client.DeleteContext(ctx, n.key(key), recursive))
+}
+
+// DeleteDir is like Client.DeleteDir within the namespace.
+func (n *Namespace) DeleteDir(key string) (*Response, error) {
+	return n.DeleteDirContext(context.Background(), key)
+}
+
+// DeleteDirContext is like DeleteDir but gives up once ctx is done.
+func (n *Namespace) DeleteDirContext(ctx context.Context, key string) (*Response, error) {
+	return n.strip(n.client.DeleteDirContext(ctx, n.key(key)))
+}
+
+// CompareAndDelete is like Client.CompareAndDelete within the namespace.
+func (n *Namespace) CompareAndDelete(key string, prevValue string, prevIndex uint64) (*Response, error) {
+	return n.CompareAndDeleteContext(context.Background(), key, prevValue, prevIndex)
+}
+
+// CompareAndDeleteContext is like CompareAndDelete but gives up once ctx
+// is done.
+func (n *Namespace) CompareAndDeleteContext(ctx context.Context, key string, prevValue string, prevIndex uint64) (*Response, error) {
+	return n.strip(n.client.CompareAndDeleteContext(ctx, n.key(key), prevValue, prevIndex))
+}
+
+// Watch is like Client.Watch within the namespace.
+func (n *Namespace) Watch(prefix string, waitIndex uint64, recursive bool,
+	receiver chan *Response, stop chan bool) (*Response, error) {
+	ctx, cancel := cancelContext(stop)
+	defer cancel()
+
+	resp, err := n.WatchContext(ctx, prefix, waitIndex, recursive, receiver)
+	if err != nil && ctx.Err() != nil {
+		return nil, ErrWatchStoppedByUser
+	}
+	return resp, err
+}
+
+// WatchContext is like Client.WatchContext within the namespace.
+func (n *Namespace) WatchContext(ctx context.Context, prefix string, waitIndex uint64, recursive bool,
+	receiver chan *Response) (*Response, error) {
+	if receiver == nil {
+		return n.strip(n.client.WatchContext(ctx, n.key(prefix), waitIndex, recursive, nil))
+	}
+
+	// pass the responses on once stripped
+	responses := make(chan *Response)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(receiver)
+		for resp := range responses {
+			n.strip(resp, nil)
+			select {
+			case receiver <- resp:
+			case <-ctx.Done():
+				for range responses {
+				}
+				return
+			}
+		}
+	}()
+
+	resp, err := n.client.WatchContext(ctx, n.key(prefix), waitIndex, recursive, responses)
+	<-done
+	return n.strip(resp, err)
+}
+
+// key returns the full key of a key in the namespace.
+func (n *Namespace) key(key string) string {
+	return path.Join(n.prefix, path.Clean("/"+key))
+}
+
+// relative returns key relative to the prefix.
+func (n *Namespace) relative(key string) string {
+	if n.prefix == "/" {
+		return key
+	}
+	if key == n.prefix {
+		return "/"
+	}
+	return strings.TrimPrefix(key, n.prefix)
+}
+
+// strip removes the prefix from the keys in resp and in err.
+func (n *Namespace) strip(resp *Response, err error) (*Response, error) {
+	if err != nil {
+		var etcdErr *EtcdError
+		if errors.As(err, &etcdErr) && (etcdErr.Cause == n.prefix || strings.HasPrefix(etcdErr.Cause, n.prefix+"/")) {
+			etcdErr.Cause = n.relative(etcdErr.Cause)
+		}
+		return resp, err
+	}
+	if resp != nil {
+		n.stripNode(resp.Node)
+		n.stripNode(resp.PrevNode)
+	}
+	return resp, nil
+}
+
+// stripNode removes the prefix from the keys of node and its children.
+func (n *Namespace) stripNode(node *Node) {
+	if node == nil {
+		return
+	}
+	node.Key = n.relative(node.Key)
+	for _, child := range node.Nodes {
+		n.stripNode(child)
+	}
+}
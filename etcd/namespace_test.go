@@ -0,0 +1,109 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestNamespace(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	ns := c.Namespace("/tenants/a")
+
+	resp, err := ns.Set("/config/port", "8080", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Node.Key != "/config/port" {
+		t.Fatalf("key = %q, want /config/port", resp.Node.Key)
+	}
+	if resp, err := c.Get("/tenants/a/config/port", false, false); err != nil || resp.Node.Value != "8080" {
+		t.Fatalf("Get = %+v, %v, want the value under the prefix", resp, err)
+	}
+
+	resp, err = ns.Set("/config/port", "9090", 0)
+	if err != nil || resp.PrevNode == nil || resp.PrevNode.Key != "/config/port" {
+		t.Fatalf("Set = %+v, %v, want a stripped previous node", resp, err)
+	}
+
+	resp, err = ns.CreateInOrder("/queue", "job", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Node.Key) <= len("/queue/") || resp.Node.Key[:len("/queue/")] != "/queue/" {
+		t.Fatalf("in order key = %q, want it under /queue", resp.Node.Key)
+	}
+
+	// nested nodes are stripped, and the root is the prefix
+	resp, err = ns.Get("/", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Node.Key != "/" || len(resp.Node.Nodes) != 2 {
+		t.Fatalf("root = %+v, want / with two children", resp.Node)
+	}
+	if config := resp.Node.Nodes[0]; config.Key != "/config" || config.Nodes[0].Key != "/config/port" {
+		t.Fatalf("config = %+v, want stripped keys", config)
+	}
+
+	// keys cannot reach out of the prefix
+	c.Set("/secret", "s", 0)
+	if _, err := ns.Get("/../../secret", false, false); !IsKeyNotFound(err) {
+		t.Fatalf("Get = %v, want key not found", err)
+	} else if cause := err.(*EtcdError).Cause; cause != "/secret" {
+		t.Fatalf("cause = %q, want /secret", cause)
+	}
+
+	if resp, err := ns.Namespace("config").Get("port", false, false); err != nil || resp.Node.Key != "/port" {
+		t.Fatalf("nested Get = %+v, %v, want /port", resp, err)
+	}
+
+	if _, err := ns.Delete("/config", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("/tenants/a/config", false, false); !IsKeyNotFound(err) {
+		t.Fatalf("Get = %v, want key not found", err)
+	}
+}
+
+func TestNamespaceWatch(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	ns := c.Namespace("tenants/a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	receiver := make(chan *Response)
+	done := make(chan error)
+	go func() {
+		_, err := ns.WatchContext(ctx, "/", 0, true, receiver)
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	c.Set("/tenants/b/key", "other", 0)
+	c.Set("/tenants/a/key", "mine", 0)
+
+	select {
+	case resp := <-receiver:
+		if resp.Node.Key != "/key" || resp.Node.Value != "mine" {
+			t.Fatalf("watched %+v, want /key", resp.Node)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change seen")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("WatchContext = %v, want %v", err, context.Canceled)
+	}
+	if _, ok := <-receiver; ok {
+		t.Fatal("receiver still open after the watch returned")
+	}
+}
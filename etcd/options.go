@@ -29,6 +29,7 @@ var (
 		"prevIndex": reflect.Uint64,
 		"prevExist": reflect.Bool,
 		"dir":       reflect.Bool,
+		"refresh":   reflect.Bool,
 	}
 
 	VALID_POST_OPTIONS = validOptions{}
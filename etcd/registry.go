@@ -0,0 +1,258 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRegistryClosed is returned when registering with a closed Registry.
+var ErrRegistryClosed = errors.New("registry is closed")
+
+// Endpoint is a registered instance of a service.
+type Endpoint struct {
+	// ID tells the instances of a service apart.
+	ID string
+	// Addr is where the instance can be reached.
+	Addr string
+}
+
+// Registry registers instances of services, and looks them up.
+//
+// An instance of a service is registered as the key dir/service/id, whose
+// value is the address of the instance. The key is created with a TTL and
+// updated with prevExist=true in the background, so that it goes away if
+// the process dies without deregistering it.
+type Registry struct {
+	client *Client
+	dir    string
+	ttl    uint64
+
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+	closed  bool
+}
+
+// registryEntry is a registered instance being kept alive.
+type registryEntry struct {
+	key  string
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// NewRegistry returns a registry of the services under dir. Instances
+// expire after ttl seconds unless refreshed; a ttl of 0 makes them
+// permanent.
+func NewRegistry(client *Client, dir string, ttl uint64) *Registry {
+	return &Registry{
+		client:  client,
+		dir:     dir,
+		ttl:     ttl,
+		entries: make(map[string]*registryEntry),
+	}
+}
+
+// Register registers the instance id of service at addr, replacing any
+// previous registration of the same instance, and keeps it alive until it
+// is deregistered.
+func (r *Registry) Register(ctx context.Context, service, id, addr string) error {
+	key := r.key(service, id)
+	if _, err := r.client.SetContext(ctx, key, addr, r.ttl); err != nil {
+		return err
+	}
+
+	refreshCtx, stop := context.WithCancel(context.Background())
+	entry := &registryEntry{key: key, stop: stop, done: make(chan struct{})}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		stop()
+		r.client.DeleteContext(ctx, key, false)
+		return ErrRegistryClosed
+	}
+	prev := r.entries[key]
+	r.entries[key] = entry
+	r.mu.Unlock()
+
+	if prev != nil {
+		prev.stop()
+		<-prev.done
+	}
+	go r.refresh(refreshCtx, entry, addr)
+
+	return nil
+}
+
+// Deregister deregisters the instance id of service.
+func (r *Registry) Deregister(ctx context.Context, service, id string) error {
+	key := r.key(service, id)
+
+	r.mu.Lock()
+	entry := r.entries[key]
+	delete(r.entries, key)
+	r.mu.Unlock()
+
+	if entry != nil {
+		entry.stop()
+		<-entry.done
+	}
+
+	_, err := r.client.DeleteContext(ctx, key, false)
+	if IsKeyNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Close deregisters all the instances registered with r, and returns the
+// first error met doing so.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = make(map[string]*registryEntry)
+	r.closed = true
+	r.mu.Unlock()
+
+	var firstErr error
+	for key, entry := range entries {
+		entry.stop()
+		<-entry.done
+
+		_, err := r.client.Delete(key, false)
+		if err != nil && !IsKeyNotFound(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Lookup returns the registered instances of service, sorted by ID.
+func (r *Registry) Lookup(ctx context.Context, service string) ([]Endpoint, error) {
+	resp, err := r.client.GetContext(ctx, path.Join(r.dir, service), false, false)
+	if IsKeyNotFound(err) {
+		return []Endpoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return endpoints(resp.Node), nil
+}
+
+// WatchEndpoints reports the registered instances of service, sorted by ID,
+// and then every new set of instances until ctx is done. Sets changing
+// faster than they are received are skipped in favor of the latest one.
+//
+// Once watching stops, the endpoint channel is closed and the reason is
+// sent on the error channel, which is then closed too. The error is
+// ctx.Err() when ctx is done.
+func (r *Registry) WatchEndpoints(ctx context.Context, service string) (<-chan []Endpoint, <-chan error) {
+	sets := make(chan []Endpoint)
+	errc := make(chan error, 1)
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	cache := NewCache(r.client, path.Join(r.dir, service), CacheHandlers{
+		OnAdd:    func(*Node) { notify() },
+		OnUpdate: func(*Node, *Node) { notify() },
+		OnDelete: func(*Node) { notify() },
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	runErr := make(chan error, 1)
+	go func() { runErr <- cache.Run(ctx) }()
+
+	go func() {
+		defer close(errc)
+		defer close(sets)
+		defer cancel()
+
+		var last []Endpoint
+		select {
+		case <-cache.Synced():
+		case err := <-runErr:
+			errc <- err
+			return
+		}
+		for {
+			root, _ := cache.Snapshot()
+			if set := endpoints(root); last == nil || !reflect.DeepEqual(set, last) {
+				select {
+				case sets <- set:
+					last = set
+				case <-ctx.Done():
+					errc <- <-runErr
+					return
+				}
+			}
+
+			select {
+			case <-changed:
+			case err := <-runErr:
+				errc <- err
+				return
+			}
+		}
+	}()
+
+	return sets, errc
+}
+
+func (r *Registry) key(service, id string) string {
+	return path.Join(r.dir, service, id)
+}
+
+// refresh renews the TTL of the entry until ctx is cancelled, and
+// registers it again if it expired meanwhile.
+func (r *Registry) refresh(ctx context.Context, entry *registryEntry, addr string) {
+	defer close(entry.done)
+
+	if r.ttl == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(r.ttl) * time.Second / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, err := r.client.UpdateContext(ctx, entry.key, addr, r.ttl)
+			if IsKeyNotFound(err) {
+				logger.Warningf("registry: %s expired, registering it again", entry.key)
+				_, err = r.client.SetContext(ctx, entry.key, addr, r.ttl)
+			}
+			if err != nil && ctx.Err() == nil {
+				logger.Warningf("registry: cannot refresh %s: %v", entry.key, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// endpoints returns the instances registered in the directory of a
+// service, sorted by ID.
+func endpoints(dir *Node) []Endpoint {
+	set := []Endpoint{}
+	if dir == nil {
+		return set
+	}
+	for _, node := range dir.Nodes {
+		if node.Dir {
+			continue
+		}
+		set = append(set, Endpoint{ID: path.Base(node.Key), Addr: node.Value})
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].ID < set[j].ID })
+	return set
+}
@@ -0,0 +1,105 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRegistry(c, "/services", 0)
+
+	sets, errc := r.WatchEndpoints(ctx, "web")
+	expectEndpoints(t, sets, []Endpoint{})
+
+	if err := r.Register(ctx, "web", "b", "10.0.0.2:80"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(ctx, "web", "a", "10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+	want := []Endpoint{{"a", "10.0.0.1:80"}, {"b", "10.0.0.2:80"}}
+	if set, err := r.Lookup(ctx, "web"); err != nil || !reflect.DeepEqual(set, want) {
+		t.Fatalf("Lookup = %v, %v, want %v", set, err, want)
+	}
+	waitForEndpoints(t, sets, want)
+
+	if err := r.Deregister(ctx, "web", "a"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEndpoints(t, sets, want[1:])
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitForEndpoints(t, sets, []Endpoint{})
+	if err := r.Register(ctx, "web", "a", "10.0.0.1:80"); err != ErrRegistryClosed {
+		t.Fatalf("Register = %v, want %v", err, ErrRegistryClosed)
+	}
+
+	cancel()
+	for range sets {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRegistryRefresh(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx := context.Background()
+	r := NewRegistry(c, "/services", 1)
+	defer r.Close()
+
+	if err := r.Register(ctx, "web", "a", "10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := c.Get("/services/web/a", false, false); err != nil {
+		t.Fatalf("registration expired while refreshed: %v", err)
+	}
+
+	// registrations lost meanwhile are made again
+	c.Delete("/services/web/a", false)
+	time.Sleep(time.Second)
+	if _, err := c.Get("/services/web/a", false, false); err != nil {
+		t.Fatalf("registration was not restored: %v", err)
+	}
+}
+
+func expectEndpoints(t *testing.T, sets <-chan []Endpoint, want []Endpoint) {
+	t.Helper()
+
+	select {
+	case set := <-sets:
+		if !reflect.DeepEqual(set, want) {
+			t.Fatalf("endpoints = %v, want %v", set, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("endpoints %v were not reported", want)
+	}
+}
+
+// waitForEndpoints skips the sets reported until want.
+func waitForEndpoints(t *testing.T, sets <-chan []Endpoint, want []Endpoint) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case set := <-sets:
+			if reflect.DeepEqual(set, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("endpoints %v were not reported", want)
+		}
+	}
+}
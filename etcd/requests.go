@@ -1,6 +1,8 @@
 package etcd
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,13 +11,13 @@ import (
 	"net/url"
 	"path"
 	"strings"
-	"sync"
 	"time"
 )
 
 // Errors introduced by handling requests
 var (
 	ErrRequestCancelled = errors.New("sending request is cancelled")
+	ErrClientClosed     = errors.New("client is closed")
 )
 
 type RawRequest struct {
@@ -23,6 +25,12 @@ type RawRequest struct {
 	RelativePath string
 	Values       url.Values
 	Cancel       <-chan bool
+
+	// toLeader sends the request to the leader even if it is a read.
+	toLeader bool
+	// body is sent as JSON instead of Values, for the APIs outside of
+	// the keys API.
+	body []byte
 }
 
 // NewRawRequest returns a new RawRequest
@@ -35,9 +43,8 @@ func NewRawRequest(method, relativePath string, values url.Values, cancel <-chan
 	}
 }
 
-// getCancelable issues a cancelable GET request
-func (c *Client) getCancelable(key string, options Options,
-	cancel <-chan bool) (*RawResponse, error) {
+// get issues a GET request
+func (c *Client) get(ctx context.Context, key string, options Options) (*RawResponse, error) {
 	logger.Debugf("get %s [%s]", key, c.cluster.pick())
 	p := keyToPath(key)
 
@@ -47,8 +54,8 @@ func (c *Client) getCancelable(key string, options Options,
 	}
 	p += str
 
-	req := NewRawRequest("GET", p, nil, cancel)
-	resp, err := c.SendRequest(req)
+	req := NewRawRequest("GET", p, nil, nil)
+	resp, err := c.SendRequestContext(ctx, req)
 
 	if err != nil {
 		return nil, err
@@ -57,13 +64,8 @@ func (c *Client) getCancelable(key string, options Options,
 	return resp, nil
 }
 
-// get issues a GET request
-func (c *Client) get(key string, options Options) (*RawResponse, error) {
-	return c.getCancelable(key, options, nil)
-}
-
 // put issues a PUT request
-func (c *Client) put(key string, value string, ttl uint64,
+func (c *Client) put(ctx context.Context, key string, value string, ttl uint64,
 	options Options) (*RawResponse, error) {
 
 	logger.Debugf("put %s, %s, ttl: %d, [%s]", key, value, ttl, c.cluster.pick())
@@ -76,7 +78,7 @@ func (c *Client) put(key string, value string, ttl uint64,
 	p += str
 
 	req := NewRawRequest("PUT", p, buildValues(value, ttl), nil)
-	resp, err := c.SendRequest(req)
+	resp, err := c.SendRequestContext(ctx, req)
 
 	if err != nil {
 		return nil, err
@@ -86,12 +88,12 @@ func (c *Client) put(key string, value string, ttl uint64,
 }
 
 // post issues a POST request
-func (c *Client) post(key string, value string, ttl uint64) (*RawResponse, error) {
+func (c *Client) post(ctx context.Context, key string, value string, ttl uint64) (*RawResponse, error) {
 	logger.Debugf("post %s, %s, ttl: %d, [%s]", key, value, ttl, c.cluster.pick())
 	p := keyToPath(key)
 
 	req := NewRawRequest("POST", p, buildValues(value, ttl), nil)
-	resp, err := c.SendRequest(req)
+	resp, err := c.SendRequestContext(ctx, req)
 
 	if err != nil {
 		return nil, err
@@ -101,7 +103,7 @@ func (c *Client) post(key string, value string, ttl uint64) (*RawResponse, error
 }
 
 // delete issues a DELETE request
-func (c *Client) delete(key string, options Options) (*RawResponse, error) {
+func (c *Client) delete(ctx context.Context, key string, options Options) (*RawResponse, error) {
 	logger.Debugf("delete %s [%s]", key, c.cluster.pick())
 	p := keyToPath(key)
 
@@ -112,7 +114,7 @@ func (c *Client) delete(key string, options Options) (*RawResponse, error) {
 	p += str
 
 	req := NewRawRequest("DELETE", p, nil, nil)
-	resp, err := c.SendRequest(req)
+	resp, err := c.SendRequestContext(ctx, req)
 
 	if err != nil {
 		return nil, err
@@ -121,121 +123,122 @@ func (c *Client) delete(key string, options Options) (*RawResponse, error) {
 	return resp, nil
 }
 
-// SendRequest sends a HTTP request and returns a Response as defined by etcd
+// SendRequest sends a HTTP request and returns a Response as defined by etcd.
+// If rr.Cancel fires before the request completes, ErrRequestCancelled
+// is returned.
 func (c *Client) SendRequest(rr *RawRequest) (*RawResponse, error) {
+	ctx, cancel := cancelContext(rr.Cancel)
+	defer cancel()
+
+	resp, err := c.SendRequestContext(ctx, rr)
+	if err != nil && ctx.Err() != nil {
+		logger.Debug("send.request is cancelled")
+		return nil, ErrRequestCancelled
+	}
+	return resp, err
+}
+
+// SendRequestContext is like SendRequest, but the request is bound to ctx
+// instead of rr.Cancel. The deadline of ctx covers every attempt, the
+// backoff between attempts and the reading of the response body; once ctx
+// is done, ctx.Err() is returned.
+func (c *Client) SendRequestContext(ctx context.Context, rr *RawRequest) (*RawResponse, error) {
 	var req *http.Request
 	var resp *http.Response
 	var httpPath string
 	var err error
 	var respBody []byte
 
-	var numReqs = 1
+	policy := c.retryPolicy()
+	start := time.Now()
+	var delay time.Duration
 
-	checkRetry := c.CheckRetry
-	if checkRetry == nil {
-		checkRetry = DefaultCheckRetry
-	}
-
-	cancelled := make(chan bool, 1)
-	reqLock := new(sync.Mutex)
-
-	if rr.Cancel != nil {
-		cancelRoutine := make(chan bool)
-		defer close(cancelRoutine)
-
-		go func() {
-			select {
-			case <-rr.Cancel:
-				cancelled <- true
-				logger.Debug("send.request is cancelled")
-			case <-cancelRoutine:
-				return
-			}
+	// Writes, and all requests under STRONG_CONSISTENCY, go straight to the
+	// leader if it is known, rather than through a redirect.
+	toLeader := rr.toLeader || (rr.Method != "GET" && rr.Method != "HEAD") ||
+		c.consistency() == STRONG_CONSISTENCY
+	var machine string
 
-			// Repeat canceling request until this thread is stopped
-			// because we have no idea about whether it succeeds.
-			for {
-				reqLock.Lock()
-				c.httpClient.Transport.(*http.Transport).CancelRequest(req)
-				reqLock.Unlock()
-
-				select {
-				case <-time.After(100 * time.Millisecond):
-				case <-cancelRoutine:
-					return
-				}
-			}
-		}()
-	}
-
-	// If we connect to a follower and consistency is required, retry until
-	// we connect to a leader
-	sleep := 25 * time.Millisecond
-	maxSleep := time.Second
-
-	for attempt := 0; ; attempt++ {
-		if attempt > 0 {
+	for attempt := 1; ; attempt++ {
+		if c.isClosed() {
+			return nil, ErrClientClosed
+		}
+		if delay > 0 {
 			select {
-			case <-cancelled:
-				return nil, ErrRequestCancelled
-			case <-time.After(sleep):
-				sleep = sleep * 2
-				if sleep > maxSleep {
-					sleep = maxSleep
-				}
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
 			}
+			delay = 0
 		}
 
-		logger.Debug("Connecting to etcd: attempt ", attempt+1, " for ", rr.RelativePath)
+		logger.Debug("Connecting to etcd: attempt ", attempt, " for ", rr.RelativePath)
 
 		// get httpPath if not set
 		if httpPath == "" {
-			httpPath = c.getHttpPath(rr.RelativePath)
+			if toLeader {
+				machine = c.cluster.pickLeader()
+			} else {
+				machine = c.cluster.pick()
+			}
+			httpPath = c.getHttpPath(machine, rr.RelativePath)
 		}
 
+		c.mu.RLock()
+		cURLch, credentials, httpClient := c.cURLch, c.credentials, c.httpClient
+		c.mu.RUnlock()
+
 		// Return a cURL command if curlChan is set
-		if c.cURLch != nil {
+		if cURLch != nil {
 			command := fmt.Sprintf("curl -X %s %s", rr.Method, httpPath)
+			// value and ttl come first, in the order buildValues sets them
+			for _, key := range []string{"value", "ttl"} {
+				if value, ok := rr.Values[key]; ok {
+					command += fmt.Sprintf(" -d %s=%s", key, value[0])
+				}
+			}
 			for key, value := range rr.Values {
-				command += fmt.Sprintf(" -d %s=%s", key, value[0])
+				if key != "value" && key != "ttl" {
+					command += fmt.Sprintf(" -d %s=%s", key, value[0])
+				}
+			}
+			if rr.body != nil {
+				command += fmt.Sprintf(" -H Content-Type:application/json -d '%s'", rr.body)
 			}
-			if c.credentials != nil {
-				command += fmt.Sprintf(" -u %s", c.credentials.username)
+			if credentials != nil {
+				command += fmt.Sprintf(" -u %s", credentials.username)
 			}
-			c.sendCURL(command)
+			sendCURL(cURLch, command)
 		}
 
 		logger.Debug("send.request.to ", httpPath, " | method ", rr.Method)
 
-		req, err := func() (*http.Request, error) {
-			reqLock.Lock()
-			defer reqLock.Unlock()
-
-			if rr.Values == nil {
-				if req, err = http.NewRequest(rr.Method, httpPath, nil); err != nil {
-					return nil, err
-				}
-			} else {
-				body := strings.NewReader(rr.Values.Encode())
-				if req, err = http.NewRequest(rr.Method, httpPath, body); err != nil {
-					return nil, err
-				}
+		if rr.body != nil {
+			if req, err = http.NewRequest(rr.Method, httpPath, bytes.NewReader(rr.body)); err != nil {
+				return nil, err
+			}
 
-				req.Header.Set("Content-Type",
-					"application/x-www-form-urlencoded; param=value")
+			req.Header.Set("Content-Type", "application/json")
+		} else if rr.Values == nil {
+			if req, err = http.NewRequest(rr.Method, httpPath, nil); err != nil {
+				return nil, err
+			}
+		} else {
+			body := strings.NewReader(rr.Values.Encode())
+			if req, err = http.NewRequest(rr.Method, httpPath, body); err != nil {
+				return nil, err
 			}
-			return req, nil
-		}()
 
-		if err != nil {
-			return nil, err
+			req.Header.Set("Content-Type",
+				"application/x-www-form-urlencoded; param=value")
 		}
+		req = req.WithContext(ctx)
 
-		if c.credentials != nil {
-			req.SetBasicAuth(c.credentials.username, c.credentials.password)
+		if credentials != nil {
+			req.SetBasicAuth(credentials.username, credentials.password)
 		}
 
-		resp, err = c.httpClient.Do(req)
+		resp, err = httpClient.Do(req)
 		// clear previous httpPath
 		httpPath = ""
 		defer func() {
@@ -244,30 +247,44 @@ func (c *Client) SendRequest(rr *RawRequest) (*RawResponse, error) {
 			}
 		}()
 
-		// If the request was cancelled, return ErrRequestCancelled directly
-		select {
-		case <-cancelled:
-			return nil, ErrRequestCancelled
-		default:
+		// If the request was cancelled, return the context error directly
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
 
-		numReqs++
+		failed := &Attempt{
+			Method:   rr.Method,
+			Num:      attempt,
+			Members:  c.cluster.machines(),
+			Elapsed:  time.Since(start),
+			Response: resp,
+			Err:      err,
+		}
 
 		// network error, change a machine!
 		if err != nil {
 			logger.Debug("network error: ", err.Error())
-			lastResp := http.Response{}
-			if checkErr := checkRetry(c.cluster, numReqs, lastResp, err); checkErr != nil {
-				return nil, checkErr
+			if delay, err = c.retryDelay(ctx, policy, failed); err != nil {
+				return nil, err
 			}
 
-			c.cluster.failure()
+			// a dead leader is forgotten, a dead member is moved away from
+			if !c.cluster.leaderFailed(machine) {
+				c.cluster.failure()
+			}
 			continue
 		}
 
 		// if there is no error, it should receive response
 		logger.Debug("recv.response.from ", httpPath)
 
+		// the HTTP client follows redirects by itself, which followers
+		// answer with to send requests to the leader
+		if u := resp.Request.URL; u.Host != req.URL.Host {
+			machine = u.Scheme + "://" + u.Host
+			c.redirectedTo(machine)
+		}
+
 		if validHttpStatusCode[resp.StatusCode] {
 			// try to read byte code and break the loop
 			respBody, err = ioutil.ReadAll(resp.Body)
@@ -276,10 +293,8 @@ func (c *Client) SendRequest(rr *RawRequest) (*RawResponse, error) {
 				break
 			}
 			// ReadAll error may be caused due to cancel request
-			select {
-			case <-cancelled:
-				return nil, ErrRequestCancelled
-			default:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
 
 			if err == io.ErrUnexpectedEOF {
@@ -300,15 +315,20 @@ func (c *Client) SendRequest(rr *RawRequest) (*RawResponse, error) {
 			} else {
 				// set httpPath for following redirection
 				httpPath = u.String()
+				// followers redirect to the leader
+				machine = u.Scheme + "://" + u.Host
+				c.redirectedTo(machine)
 			}
 			resp.Body.Close()
 			continue
 		}
 
-		if checkErr := checkRetry(c.cluster, numReqs, *resp,
-			errors.New("Unexpected HTTP status code")); checkErr != nil {
-			return nil, checkErr
+		failed.Err = errors.New("Unexpected HTTP status code")
+		if delay, err = c.retryDelay(ctx, policy, failed); err != nil {
+			return nil, err
 		}
+		// the leader may have stepped down
+		c.cluster.leaderFailed(machine)
 		resp.Body.Close()
 	}
 
@@ -321,14 +341,55 @@ func (c *Client) SendRequest(rr *RawRequest) (*RawResponse, error) {
 	return r, nil
 }
 
+// cancelContext returns a context that is cancelled as soon as the given
+// channel receives a value or is closed. A nil channel never cancels it.
+func cancelContext(cancel <-chan bool) (context.Context, context.CancelFunc) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	if cancel != nil {
+		go func() {
+			select {
+			case <-cancel:
+				cancelFunc()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancelFunc
+}
+
+// retryDelay asks policy whether a failed attempt may be followed by
+// another one, and how long to wait for it. A done ctx always stops the
+// retries.
+func (c *Client) retryDelay(ctx context.Context, policy RetryPolicy, failed *Attempt) (time.Duration, error) {
+	if err := policy.ShouldRetry(failed); err != nil {
+		return 0, err
+	}
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return policy.Delay(failed), nil
+}
+
+// redirectedTo records machine, which a request was redirected to, as the
+// leader. The redirect is still followed if machine is not a member, but
+// the leader is left as it is.
+func (c *Client) redirectedTo(machine string) {
+	if !c.cluster.setMemberLeader(machine) {
+		logger.Warningf("redirected to %s, which is not a member", machine)
+	}
+}
+
 // DefaultCheckRetry defines the retrying behaviour for bad HTTP requests
 // If we have retried 2 * machine number, stop retrying.
 // If status code is InternalServerError, sleep for 200ms.
+//
+// Deprecated: the default RetryPolicy supersedes it.
 func DefaultCheckRetry(cluster *Cluster, numReqs int, lastResp http.Response,
 	err error) error {
 
-	if numReqs > 2*len(cluster.Machines) {
-		errStr := fmt.Sprintf("failed to propose on members %v twice [last error: %v]", cluster.Machines, err)
+	machines := cluster.machines()
+	if numReqs > 2*len(machines) {
+		errStr := fmt.Sprintf("failed to propose on members %v twice [last error: %v]", machines, err)
 		return newError(ErrCodeEtcdNotReachable, errStr, 0)
 	}
 
@@ -337,14 +398,7 @@ func DefaultCheckRetry(cluster *Cluster, numReqs int, lastResp http.Response,
 		return nil
 	}
 	if !shouldRetry(lastResp) {
-		body := []byte("nil")
-		if lastResp.Body != nil {
-			if b, err := ioutil.ReadAll(lastResp.Body); err == nil {
-				body = b
-			}
-		}
-		errStr := fmt.Sprintf("unhandled http status [%s] with body [%s]", http.StatusText(lastResp.StatusCode), body)
-		return newError(ErrCodeUnhandledHTTPStatus, errStr, 0)
+		return unhandledStatusError(&lastResp)
 	}
 	// sleep some time and expect leader election finish
 	time.Sleep(time.Millisecond * 200)
@@ -352,6 +406,19 @@ func DefaultCheckRetry(cluster *Cluster, numReqs int, lastResp http.Response,
 	return nil
 }
 
+// unhandledStatusError returns the error for a response whose status
+// cannot be dealt with.
+func unhandledStatusError(resp *http.Response) error {
+	body := []byte("nil")
+	if resp.Body != nil {
+		if b, err := ioutil.ReadAll(resp.Body); err == nil {
+			body = b
+		}
+	}
+	errStr := fmt.Sprintf("unhandled http status [%s] with body [%s]", http.StatusText(resp.StatusCode), body)
+	return newError(ErrCodeUnhandledHTTPStatus, errStr, 0)
+}
+
 func isEmptyResponse(r http.Response) bool { return r.StatusCode == 0 }
 
 // shouldRetry returns whether the reponse deserves retry.
@@ -361,8 +428,8 @@ func shouldRetry(r http.Response) bool {
 	return r.StatusCode == http.StatusInternalServerError
 }
 
-func (c *Client) getHttpPath(s ...string) string {
-	fullPath := c.cluster.pick() + "/" + version
+func (c *Client) getHttpPath(machine string, s ...string) string {
+	fullPath := machine + "/" + version
 	for _, seg := range s {
 		fullPath = fullPath + "/" + seg
 	}
@@ -1,6 +1,15 @@
 package etcd
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
 
 func TestKeyToPath(t *testing.T) {
 	tests := []struct {
@@ -13,6 +22,7 @@ func TestKeyToPath(t *testing.T) {
 		{"%z", "keys/%25z"},
 		{"/", "keys/"},
 	}
+
 	for i, tt := range tests {
 		path := keyToPath(tt.key)
 		if path != tt.wpath {
@@ -20,3 +30,201 @@ func TestKeyToPath(t *testing.T) {
 		}
 	}
 }
+
+// newHangingServer returns a server which never answers until the
+// request is abandoned by the client.
+func newHangingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+}
+
+func TestSendRequestContext(t *testing.T) {
+	s := newHangingServer()
+	defer s.Close()
+
+	c := NewClient([]string{s.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetContext(ctx, "foo", false, false)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("request took %v to notice the deadline", d)
+	}
+}
+
+func TestSendRequestCancel(t *testing.T) {
+	s := newHangingServer()
+	defer s.Close()
+
+	c := NewClient([]string{s.URL})
+
+	cancel := make(chan bool)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(cancel)
+	}()
+
+	_, err := c.SendRequest(NewRawRequest("GET", "keys/foo", nil, cancel))
+	if err != ErrRequestCancelled {
+		t.Fatalf("err = %v, want %v", err, ErrRequestCancelled)
+	}
+
+	stop := make(chan bool, 1)
+	stop <- true
+
+	_, err = c.Watch("foo", 0, false, nil, stop)
+	if err != ErrWatchStoppedByUser {
+		t.Fatalf("err = %v, want %v", err, ErrWatchStoppedByUser)
+	}
+}
+
+func TestRetryDelayContext(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	c := NewClient([]string{s.URL})
+	c.RetryPolicy = &BackoffRetryPolicy{MinDelay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The delays between retries must not outlive the deadline.
+	start := time.Now()
+	_, err := c.SetContext(ctx, "foo", "bar", 0)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Fatalf("retry took %v to notice the deadline", d)
+	}
+}
+
+func TestSendRequestRedirect(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	follower, leader := cluster.Members[0], cluster.Members[1]
+	follower.Inject(etcdtest.Redirect(leader), 1)
+
+	c := NewClient([]string{follower.URL})
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if leader.Requests() != 1 {
+		t.Fatalf("leader received %d requests, want 1", leader.Requests())
+	}
+}
+
+func TestSendRequestLeaderElection(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	m := cluster.Members[0]
+	m.Inject(etcdtest.Status(http.StatusInternalServerError), 1)
+
+	c := NewClient(cluster.URLs())
+	resp, err := c.Set("foo", "bar", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Node.Value != "bar" {
+		t.Fatalf("value = %s, want bar", resp.Node.Value)
+	}
+	if m.Requests() != 2 {
+		t.Fatalf("member received %d requests, want 2", m.Requests())
+	}
+}
+
+func TestSendRequestUnexpectedEOF(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	cluster.Members[0].Inject(etcdtest.DropBody(), 1)
+
+	// The truncated body is handed out empty rather than retried.
+	c := NewClient(cluster.URLs())
+	raw, err := c.RawGet("foo", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.StatusCode != http.StatusOK || len(raw.Body) != 0 {
+		t.Fatalf("raw = %d %q, want 200 with an empty body", raw.StatusCode, raw.Body)
+	}
+}
+
+func TestSendRequestDeadMember(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	picked := c.cluster.pick()
+	for _, m := range cluster.Members {
+		if m.URL == picked {
+			m.Kill()
+		}
+	}
+
+	if _, err := c.Set("foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c.cluster.pick() == picked {
+		t.Fatalf("client still uses the dead member %s", picked)
+	}
+
+	for _, m := range cluster.Members {
+		m.Kill()
+	}
+	_, err := c.Get("foo", false, false)
+	if etcdErr, ok := err.(*EtcdError); !ok || etcdErr.ErrorCode != ErrCodeEtcdNotReachable {
+		t.Fatalf("err = %v, want error code %d", err, ErrCodeEtcdNotReachable)
+	}
+}
+
+func TestSendRequestSlowMember(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	cluster.Members[0].Inject(etcdtest.Delay(time.Second), 0)
+
+	c := NewClient(cluster.URLs())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetContext(ctx, "foo", false, false); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCustomCheckRetry(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	cluster.Members[0].Inject(etcdtest.Status(http.StatusServiceUnavailable), 0)
+
+	errGiveUp := errors.New("giving up")
+	var calls []int
+
+	c := NewClient(cluster.URLs())
+	c.CheckRetry = func(cl *Cluster, numReqs int, lastResp http.Response, err error) error {
+		calls = append(calls, lastResp.StatusCode)
+		if numReqs > 3 {
+			return errGiveUp
+		}
+		return nil
+	}
+
+	if _, err := c.Set("foo", "bar", 0); err != errGiveUp {
+		t.Fatalf("err = %v, want %v", err, errGiveUp)
+	}
+	if len(calls) != 3 || calls[0] != http.StatusServiceUnavailable {
+		t.Fatalf("CheckRetry calls = %v, want 3 for status 503", calls)
+	}
+}
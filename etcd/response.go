@@ -27,6 +27,9 @@ var (
 	validHttpStatusCode = map[int]bool{
 		http.StatusCreated:            true,
 		http.StatusOK:                 true,
+		http.StatusNoContent:          true,
+		http.StatusConflict:           true,
+		http.StatusGone:               true,
 		http.StatusBadRequest:         true,
 		http.StatusNotFound:           true,
 		http.StatusPreconditionFailed: true,
@@ -38,7 +41,14 @@ var (
 // Unmarshal parses RawResponse and stores the result in Response
 func (rr *RawResponse) Unmarshal() (*Response, error) {
 	if rr.StatusCode != http.StatusOK && rr.StatusCode != http.StatusCreated {
-		return nil, handleError(rr.Body)
+		err := handleError(rr.Body)
+		// etcd refuses requests lacking permissions with a bare message
+		if etcdErr, ok := err.(*EtcdError); ok && etcdErr.ErrorCode == 0 && rr.StatusCode == http.StatusUnauthorized {
+			etcdErr.ErrorCode = ErrCodeUnauthorized
+			etcdErr.Cause = etcdErr.Message
+			etcdErr.Message = errorMap[ErrCodeUnauthorized]
+		}
+		return nil, err
 	}
 
 	resp := new(Response)
@@ -0,0 +1,188 @@
+package etcd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Attempt is a failed attempt to send a request, as seen by a RetryPolicy.
+type Attempt struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Num is the number of attempts made so far, this one included.
+	Num int
+	// Members are the client URLs of the cluster.
+	Members []string
+	// Elapsed is the time spent since the first attempt was sent.
+	Elapsed time.Duration
+	// Response is the response to the attempt, or nil if none was received.
+	Response *http.Response
+	// Err is the reason of the failure.
+	Err error
+}
+
+// RetryPolicy decides whether and when failed requests are sent again.
+// It is called by a single goroutine per request, but for all the
+// requests of a Client at once.
+type RetryPolicy interface {
+	// ShouldRetry returns nil if the request may be sent again after the
+	// failed attempt, or the error to give up with.
+	ShouldRetry(failed *Attempt) error
+	// Delay returns how long to wait before sending the request again.
+	Delay(failed *Attempt) time.Duration
+}
+
+// BackoffRetryPolicy retries requests with exponentially growing delays,
+// until attempts or time run out.
+//
+// Responses with a 500, 502, 503 or 504 status are retried, as are
+// network errors. Requests that are not idempotent, that is POST requests
+// such as those of CreateInOrder, are only retried if they surely never
+// reached etcd, unless ReplayNonIdempotent is set.
+type BackoffRetryPolicy struct {
+	// MaxAttempts is the number of attempts after which to give up. If 0,
+	// each member of the cluster is tried twice.
+	MaxAttempts int
+	// Deadline bounds the time spent on a request, all attempts included.
+	// If 0, only MaxAttempts applies.
+	Deadline time.Duration
+	// MinDelay is the delay before the first retry. It doubles after each
+	// retry, up to MaxDelay if set.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// Jitter randomizes delays by up to this fraction of them either way,
+	// so that clients failing together do not retry together.
+	Jitter float64
+	// ReplayNonIdempotent makes requests that are not idempotent be sent
+	// again even though etcd might have carried them out already.
+	ReplayNonIdempotent bool
+}
+
+// NewBackoffRetryPolicy returns the policy used by clients without a
+// RetryPolicy or a CheckRetry: each member is tried twice, with delays
+// growing from 25ms to 1s, jittered by 20%.
+func NewBackoffRetryPolicy() *BackoffRetryPolicy {
+	return &BackoffRetryPolicy{
+		MinDelay: 25 * time.Millisecond,
+		MaxDelay: time.Second,
+		Jitter:   0.2,
+	}
+}
+
+// retryStatusCodes are the statuses of the responses worth retrying.
+var retryStatusCodes = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// ShouldRetry implements RetryPolicy.
+func (p *BackoffRetryPolicy) ShouldRetry(failed *Attempt) error {
+	if failed.Response != nil && !retryStatusCodes[failed.Response.StatusCode] {
+		return unhandledStatusError(failed.Response)
+	}
+
+	if !p.ReplayNonIdempotent && !isIdempotent(failed.Method) && !neverSent(failed) {
+		if failed.Response != nil {
+			return unhandledStatusError(failed.Response)
+		}
+		return failed.Err
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 2 * len(failed.Members)
+	}
+	if failed.Num >= maxAttempts {
+		errStr := fmt.Sprintf("failed to propose on members %v after %d attempts [last error: %v]",
+			failed.Members, failed.Num, failed.Err)
+		return newError(ErrCodeEtcdNotReachable, errStr, 0)
+	}
+	if p.Deadline > 0 && failed.Elapsed >= p.Deadline {
+		errStr := fmt.Sprintf("failed to propose on members %v within %v [last error: %v]",
+			failed.Members, p.Deadline, failed.Err)
+		return newError(ErrCodeEtcdNotReachable, errStr, 0)
+	}
+
+	if failed.Response != nil {
+		logger.Warning("bad response status code ", failed.Response.StatusCode)
+	}
+	return nil
+}
+
+// Delay implements RetryPolicy.
+func (p *BackoffRetryPolicy) Delay(failed *Attempt) time.Duration {
+	d := p.MinDelay
+	for i := 1; i < failed.Num && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+
+	// do not wait past the deadline
+	if p.Deadline > 0 && failed.Elapsed+d > p.Deadline {
+		d = p.Deadline - failed.Elapsed
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// isIdempotent reports whether sending a request with the given method
+// twice has the same effect as sending it once.
+func isIdempotent(method string) bool {
+	return method != "POST"
+}
+
+// neverSent reports whether the failed attempt surely never reached etcd,
+// because no connection could be made.
+func neverSent(failed *Attempt) bool {
+	var opErr *net.OpError
+	return failed.Response == nil && errors.As(failed.Err, &opErr) && opErr.Op == "dial"
+}
+
+// checkRetryPolicy makes a RetryPolicy of a Client.CheckRetry function.
+type checkRetryPolicy struct {
+	cluster    *Cluster
+	checkRetry func(cluster *Cluster, numReqs int, lastResp http.Response, err error) error
+}
+
+// ShouldRetry passes the attempt on to the CheckRetry function, which
+// counts one request more than there were attempts.
+func (p *checkRetryPolicy) ShouldRetry(failed *Attempt) error {
+	var lastResp http.Response
+	if failed.Response != nil {
+		lastResp = *failed.Response
+	}
+	return p.checkRetry(p.cluster, failed.Num+1, lastResp, failed.Err)
+}
+
+// Delay waits as clients did before RetryPolicy was introduced.
+func (p *checkRetryPolicy) Delay(failed *Attempt) time.Duration {
+	backoff := BackoffRetryPolicy{MinDelay: 25 * time.Millisecond, MaxDelay: time.Second}
+	return backoff.Delay(failed)
+}
+
+// retryPolicy returns the policy for the failed requests of c.
+func (c *Client) retryPolicy() RetryPolicy {
+	switch {
+	case c.RetryPolicy != nil:
+		return c.RetryPolicy
+	case c.CheckRetry != nil:
+		return &checkRetryPolicy{cluster: c.cluster, checkRetry: c.CheckRetry}
+	}
+	return defaultRetryPolicy
+}
+
+var defaultRetryPolicy = NewBackoffRetryPolicy()
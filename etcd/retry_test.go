@@ -0,0 +1,118 @@
+package etcd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestBackoffRetryPolicyDelay(t *testing.T) {
+	p := &BackoffRetryPolicy{MinDelay: 25 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		num int
+		wd  time.Duration
+	}{
+		{1, 25 * time.Millisecond},
+		{2, 50 * time.Millisecond},
+		{3, 100 * time.Millisecond},
+		{6, 800 * time.Millisecond},
+		{7, time.Second},
+		{100, time.Second},
+	}
+	for i, tt := range tests {
+		if d := p.Delay(&Attempt{Num: tt.num}); d != tt.wd {
+			t.Errorf("#%d: delay = %v, want %v", i, d, tt.wd)
+		}
+	}
+
+	p.Deadline = time.Second
+	if d := p.Delay(&Attempt{Num: 7, Elapsed: 900 * time.Millisecond}); d != 100*time.Millisecond {
+		t.Errorf("delay = %v, want the 100ms left before the deadline", d)
+	}
+
+	p = NewBackoffRetryPolicy()
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(&Attempt{Num: 3}); d < 80*time.Millisecond || d > 120*time.Millisecond {
+			t.Fatalf("delay = %v, want 100ms ±20%%", d)
+		}
+	}
+}
+
+func TestBackoffRetryPolicyShouldRetry(t *testing.T) {
+	members := []string{"http://127.0.0.1:4001", "http://127.0.0.1:4002"}
+	dialErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+	status := func(code int) *http.Response { return &http.Response{StatusCode: code} }
+
+	tests := []struct {
+		policy BackoffRetryPolicy
+		failed Attempt
+		wretry bool
+	}{
+		{BackoffRetryPolicy{}, Attempt{Method: "GET", Num: 1, Err: readErr}, true},
+		{BackoffRetryPolicy{}, Attempt{Method: "PUT", Num: 1, Response: status(503)}, true},
+		{BackoffRetryPolicy{}, Attempt{Method: "PUT", Num: 1, Response: status(http.StatusTeapot)}, false},
+		// each member is tried twice by default
+		{BackoffRetryPolicy{}, Attempt{Method: "GET", Num: 3, Err: readErr}, true},
+		{BackoffRetryPolicy{}, Attempt{Method: "GET", Num: 4, Err: readErr}, false},
+		{BackoffRetryPolicy{MaxAttempts: 10}, Attempt{Method: "GET", Num: 4, Err: readErr}, true},
+		{BackoffRetryPolicy{Deadline: time.Second}, Attempt{Method: "GET", Num: 1, Elapsed: time.Second, Err: readErr}, false},
+		// POST requests are only replayed if they were never sent
+		{BackoffRetryPolicy{}, Attempt{Method: "POST", Num: 1, Err: dialErr}, true},
+		{BackoffRetryPolicy{}, Attempt{Method: "POST", Num: 1, Err: readErr}, false},
+		{BackoffRetryPolicy{}, Attempt{Method: "POST", Num: 1, Response: status(500)}, false},
+		{BackoffRetryPolicy{ReplayNonIdempotent: true}, Attempt{Method: "POST", Num: 1, Err: readErr}, true},
+	}
+
+	for i, tt := range tests {
+		tt.failed.Members = members
+		err := tt.policy.ShouldRetry(&tt.failed)
+		if (err == nil) != tt.wretry {
+			t.Errorf("#%d: ShouldRetry = %v, want retry %v", i, err, tt.wretry)
+		}
+	}
+}
+
+func TestRetryPolicyNonIdempotent(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	m := cluster.Members[0]
+	c := NewClient(cluster.URLs())
+
+	m.Inject(etcdtest.Hangup(), 1)
+	if _, err := c.CreateInOrder("/queue", "job", 0); err == nil {
+		t.Fatal("CreateInOrder succeeded, want the hangup reported")
+	}
+	if m.Requests() != 1 {
+		t.Fatalf("member received %d requests, want 1", m.Requests())
+	}
+
+	m.Inject(etcdtest.Hangup(), 1)
+	if _, err := c.Set("/foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCustomRetryPolicy(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	m := cluster.Members[0]
+	m.Inject(etcdtest.Status(http.StatusServiceUnavailable), 0)
+
+	c := NewClient(cluster.URLs())
+	c.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 3}
+	_, err := c.Set("foo", "bar", 0)
+	if !isErrorCode(err, ErrCodeEtcdNotReachable) {
+		t.Fatalf("err = %v, want error code %d", err, ErrCodeEtcdNotReachable)
+	}
+	if m.Requests() != 3 {
+		t.Fatalf("member received %d requests, want 3", m.Requests())
+	}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 func TestSetCurlChan(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	c.OpenCURL()
 
 	defer func() {
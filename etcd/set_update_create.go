@@ -1,10 +1,17 @@
 package etcd
 
+import "context"
+
 // Set sets the given key to the given value.
 // It will create a new key value pair or replace the old one.
 // It will not replace a existing directory.
 func (c *Client) Set(key string, value string, ttl uint64) (*Response, error) {
-	raw, err := c.RawSet(key, value, ttl)
+	return c.SetContext(context.Background(), key, value, ttl)
+}
+
+// SetContext is like Set but gives up once ctx is done.
+func (c *Client) SetContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	raw, err := c.rawSet(ctx, key, value, ttl)
 
 	if err != nil {
 		return nil, err
@@ -17,7 +24,12 @@ func (c *Client) Set(key string, value string, ttl uint64) (*Response, error) {
 // It will create a new directory or replace the old key value pair by a directory.
 // It will not replace a existing directory.
 func (c *Client) SetDir(key string, ttl uint64) (*Response, error) {
-	raw, err := c.RawSetDir(key, ttl)
+	return c.SetDirContext(context.Background(), key, ttl)
+}
+
+// SetDirContext is like SetDir but gives up once ctx is done.
+func (c *Client) SetDirContext(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	raw, err := c.rawSetDir(ctx, key, ttl)
 
 	if err != nil {
 		return nil, err
@@ -29,7 +41,12 @@ func (c *Client) SetDir(key string, ttl uint64) (*Response, error) {
 // CreateDir creates a directory. It succeeds only if
 // the given key does not yet exist.
 func (c *Client) CreateDir(key string, ttl uint64) (*Response, error) {
-	raw, err := c.RawCreateDir(key, ttl)
+	return c.CreateDirContext(context.Background(), key, ttl)
+}
+
+// CreateDirContext is like CreateDir but gives up once ctx is done.
+func (c *Client) CreateDirContext(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	raw, err := c.rawCreateDir(ctx, key, ttl)
 
 	if err != nil {
 		return nil, err
@@ -41,7 +58,12 @@ func (c *Client) CreateDir(key string, ttl uint64) (*Response, error) {
 // UpdateDir updates the given directory. It succeeds only if the
 // given key already exists.
 func (c *Client) UpdateDir(key string, ttl uint64) (*Response, error) {
-	raw, err := c.RawUpdateDir(key, ttl)
+	return c.UpdateDirContext(context.Background(), key, ttl)
+}
+
+// UpdateDirContext is like UpdateDir but gives up once ctx is done.
+func (c *Client) UpdateDirContext(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	raw, err := c.rawUpdateDir(ctx, key, ttl)
 
 	if err != nil {
 		return nil, err
@@ -53,7 +75,12 @@ func (c *Client) UpdateDir(key string, ttl uint64) (*Response, error) {
 // Create creates a file with the given value under the given key.  It succeeds
 // only if the given key does not yet exist.
 func (c *Client) Create(key string, value string, ttl uint64) (*Response, error) {
-	raw, err := c.RawCreate(key, value, ttl)
+	return c.CreateContext(context.Background(), key, value, ttl)
+}
+
+// CreateContext is like Create but gives up once ctx is done.
+func (c *Client) CreateContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	raw, err := c.rawCreate(ctx, key, value, ttl)
 
 	if err != nil {
 		return nil, err
@@ -65,7 +92,12 @@ func (c *Client) Create(key string, value string, ttl uint64) (*Response, error)
 // CreateInOrder creates a file with a key that's guaranteed to be higher than other
 // keys in the given directory. It is useful for creating queues.
 func (c *Client) CreateInOrder(dir string, value string, ttl uint64) (*Response, error) {
-	raw, err := c.RawCreateInOrder(dir, value, ttl)
+	return c.CreateInOrderContext(context.Background(), dir, value, ttl)
+}
+
+// CreateInOrderContext is like CreateInOrder but gives up once ctx is done.
+func (c *Client) CreateInOrderContext(ctx context.Context, dir string, value string, ttl uint64) (*Response, error) {
+	raw, err := c.rawCreateInOrder(ctx, dir, value, ttl)
 
 	if err != nil {
 		return nil, err
@@ -77,7 +109,29 @@ func (c *Client) CreateInOrder(dir string, value string, ttl uint64) (*Response,
 // Update updates the given key to the given value.  It succeeds only if the
 // given key already exists.
 func (c *Client) Update(key string, value string, ttl uint64) (*Response, error) {
-	raw, err := c.RawUpdate(key, value, ttl)
+	return c.UpdateContext(context.Background(), key, value, ttl)
+}
+
+// UpdateContext is like Update but gives up once ctx is done.
+func (c *Client) UpdateContext(ctx context.Context, key string, value string, ttl uint64) (*Response, error) {
+	raw, err := c.rawUpdate(ctx, key, value, ttl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.Unmarshal()
+}
+
+// refreshTTL renews the TTL of an existing key, keeping its value and
+// without waking up the watchers of the key.
+func (c *Client) refreshTTL(ctx context.Context, key string, ttl uint64) (*Response, error) {
+	ops := Options{
+		"prevExist": true,
+		"refresh":   true,
+	}
+
+	raw, err := c.put(ctx, key, "", ttl, ops)
 
 	if err != nil {
 		return nil, err
@@ -87,51 +141,79 @@ func (c *Client) Update(key string, value string, ttl uint64) (*Response, error)
 }
 
 func (c *Client) RawUpdateDir(key string, ttl uint64) (*RawResponse, error) {
+	return c.rawUpdateDir(context.Background(), key, ttl)
+}
+
+func (c *Client) rawUpdateDir(ctx context.Context, key string, ttl uint64) (*RawResponse, error) {
 	ops := Options{
 		"prevExist": true,
 		"dir":       true,
 	}
 
-	return c.put(key, "", ttl, ops)
+	return c.put(ctx, key, "", ttl, ops)
 }
 
 func (c *Client) RawCreateDir(key string, ttl uint64) (*RawResponse, error) {
+	return c.rawCreateDir(context.Background(), key, ttl)
+}
+
+func (c *Client) rawCreateDir(ctx context.Context, key string, ttl uint64) (*RawResponse, error) {
 	ops := Options{
 		"prevExist": false,
 		"dir":       true,
 	}
 
-	return c.put(key, "", ttl, ops)
+	return c.put(ctx, key, "", ttl, ops)
 }
 
 func (c *Client) RawSet(key string, value string, ttl uint64) (*RawResponse, error) {
-	return c.put(key, value, ttl, nil)
+	return c.rawSet(context.Background(), key, value, ttl)
+}
+
+func (c *Client) rawSet(ctx context.Context, key string, value string, ttl uint64) (*RawResponse, error) {
+	return c.put(ctx, key, value, ttl, nil)
 }
 
 func (c *Client) RawSetDir(key string, ttl uint64) (*RawResponse, error) {
+	return c.rawSetDir(context.Background(), key, ttl)
+}
+
+func (c *Client) rawSetDir(ctx context.Context, key string, ttl uint64) (*RawResponse, error) {
 	ops := Options{
 		"dir": true,
 	}
 
-	return c.put(key, "", ttl, ops)
+	return c.put(ctx, key, "", ttl, ops)
 }
 
 func (c *Client) RawUpdate(key string, value string, ttl uint64) (*RawResponse, error) {
+	return c.rawUpdate(context.Background(), key, value, ttl)
+}
+
+func (c *Client) rawUpdate(ctx context.Context, key string, value string, ttl uint64) (*RawResponse, error) {
 	ops := Options{
 		"prevExist": true,
 	}
 
-	return c.put(key, value, ttl, ops)
+	return c.put(ctx, key, value, ttl, ops)
 }
 
 func (c *Client) RawCreate(key string, value string, ttl uint64) (*RawResponse, error) {
+	return c.rawCreate(context.Background(), key, value, ttl)
+}
+
+func (c *Client) rawCreate(ctx context.Context, key string, value string, ttl uint64) (*RawResponse, error) {
 	ops := Options{
 		"prevExist": false,
 	}
 
-	return c.put(key, value, ttl, ops)
+	return c.put(ctx, key, value, ttl, ops)
 }
 
 func (c *Client) RawCreateInOrder(dir string, value string, ttl uint64) (*RawResponse, error) {
-	return c.post(dir, value, ttl)
+	return c.rawCreateInOrder(context.Background(), dir, value, ttl)
+}
+
+func (c *Client) rawCreateInOrder(ctx context.Context, dir string, value string, ttl uint64) (*RawResponse, error) {
+	return c.post(ctx, dir, value, ttl)
 }
@@ -5,7 +5,7 @@ import (
 )
 
 func TestSet(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("foo", true)
 	}()
@@ -34,7 +34,7 @@ func TestSet(t *testing.T) {
 }
 
 func TestUpdate(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("foo", true)
 		c.Delete("nonexistent", true)
@@ -68,7 +68,7 @@ func TestUpdate(t *testing.T) {
 }
 
 func TestCreate(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("newKey", true)
 	}()
@@ -99,7 +99,7 @@ func TestCreate(t *testing.T) {
 }
 
 func TestCreateInOrder(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	dir := "/queue"
 	defer func() {
 		c.DeleteDir(dir)
@@ -136,7 +136,7 @@ func TestCreateInOrder(t *testing.T) {
 }
 
 func TestSetDir(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("foo", true)
 		c.Delete("fooDir", true)
@@ -180,7 +180,7 @@ func TestSetDir(t *testing.T) {
 }
 
 func TestUpdateDir(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("fooDir", true)
 	}()
@@ -213,7 +213,7 @@ func TestUpdateDir(t *testing.T) {
 }
 
 func TestCreateDir(t *testing.T) {
-	c := NewClient(nil)
+	c := newTestClient(t)
 	defer func() {
 		c.Delete("fooDir", true)
 	}()
@@ -0,0 +1,141 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SRVResolver looks up DNS SRV records. *net.Resolver implements it.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// srvServices are the services etcd members are published as, with the
+// scheme of their client URLs.
+var srvServices = []struct {
+	service, scheme string
+}{
+	{"etcd-client-ssl", "https"},
+	{"etcd-client", "http"},
+}
+
+// srvSource finds the machines of a cluster from the SRV records of a
+// domain.
+type srvSource struct {
+	domain   string
+	resolver SRVResolver
+}
+
+// NewClientFromSRV creates a client for the cluster published in the
+// _etcd-client-ssl._tcp and _etcd-client._tcp SRV records of domain, the
+// former giving https machines and the latter http ones. The resolver
+// defaults to net.DefaultResolver if nil.
+//
+// The client sends requests to the machines in the order the records
+// rank them: by priority first, then at random favoring the heavier
+// records. SyncCluster resolves the records again, and falls back on
+// them if none of the machines can be reached.
+func NewClientFromSRV(domain string, resolver SRVResolver) (*Client, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	srv := &srvSource{domain: domain, resolver: resolver}
+	machines, err := srv.resolve(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient(machines)
+	client.srv = srv
+	client.cluster.update(machines, 0)
+	client.saveConfig()
+
+	return client, nil
+}
+
+// srvRecord is a machine found in an SRV record.
+type srvRecord struct {
+	url              string
+	priority, weight int
+}
+
+// resolve returns the client URLs published for the domain, ranked by
+// priority and weight.
+func (s *srvSource) resolve(ctx context.Context) ([]string, error) {
+	var records []srvRecord
+	var lastErr error
+
+	for _, svc := range srvServices {
+		_, addrs, err := s.resolver.LookupSRV(ctx, svc.service, "tcp", s.domain)
+		if err != nil {
+			var dnsErr *net.DNSError
+			if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+				lastErr = err
+			}
+			continue
+		}
+		for _, addr := range addrs {
+			host := strings.TrimSuffix(addr.Target, ".")
+			records = append(records, srvRecord{
+				url:      svc.scheme + "://" + net.JoinHostPort(host, strconv.Itoa(int(addr.Port))),
+				priority: int(addr.Priority),
+				weight:   int(addr.Weight),
+			})
+		}
+	}
+	if len(records) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("no etcd SRV records found for %s", s.domain)
+	}
+
+	sortSRV(records)
+	machines := make([]string, len(records))
+	for i, r := range records {
+		machines[i] = r.url
+	}
+	return machines, nil
+}
+
+// sortSRV orders records by priority, and records of the same priority
+// at random, giving heavier ones better odds to come first, as described
+// in RFC 2782.
+func sortSRV(records []srvRecord) {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].priority < records[j].priority })
+
+	for i := 0; i < len(records); {
+		j := i + 1
+		for j < len(records) && records[j].priority == records[i].priority {
+			j++
+		}
+		shuffleByWeight(records[i:j])
+		i = j
+	}
+}
+
+func shuffleByWeight(records []srvRecord) {
+	sum := 0
+	for _, r := range records {
+		sum += r.weight
+	}
+	for sum > 0 && len(records) > 1 {
+		s := 0
+		n := rand.Intn(sum)
+		for i := range records {
+			s += records[i].weight
+			if s > n {
+				records[0], records[i] = records[i], records[0]
+				break
+			}
+		}
+		sum -= records[0].weight
+		records = records[1:]
+	}
+}
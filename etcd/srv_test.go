@@ -0,0 +1,165 @@
+package etcd
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+// fakeResolver answers SRV lookups from a table of services.
+type fakeResolver struct {
+	mu      sync.Mutex
+	records map[string][]*net.SRV
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cname := "_" + service + "._" + proto + "." + name + "."
+	addrs, ok := r.records[service]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: cname, IsNotFound: true}
+	}
+	return cname, addrs, nil
+}
+
+func (r *fakeResolver) set(service string, addrs ...*net.SRV) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[service] = addrs
+}
+
+// srvOf returns an SRV record pointing at the member with the given URL.
+func srvOf(t *testing.T, memberURL string, priority, weight uint16) *net.SRV {
+	u, err := url.Parse(memberURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &net.SRV{Target: u.Hostname() + ".", Port: uint16(port), Priority: priority, Weight: weight}
+}
+
+func TestNewClientFromSRV(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	r := &fakeResolver{records: map[string][]*net.SRV{}}
+	r.set("etcd-client",
+		srvOf(t, cluster.Members[2].URL, 20, 0),
+		srvOf(t, cluster.Members[0].URL, 10, 0),
+		srvOf(t, cluster.Members[1].URL, 30, 0),
+	)
+
+	c, err := NewClientFromSRV("example.com", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{cluster.Members[0].URL, cluster.Members[2].URL, cluster.Members[1].URL}
+	if got := c.GetCluster(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("machines = %v, want %v", got, want)
+	}
+
+	// the machine with the best priority is used first
+	before := cluster.Members[0].Requests()
+	if _, err := c.Get("foo", false, false); !IsKeyNotFound(err) {
+		t.Fatalf("Get = %v, want key not found", err)
+	}
+	if cluster.Members[0].Requests() != before+1 {
+		t.Fatal("request did not go to the machine with the best priority")
+	}
+}
+
+func TestSyncClusterKeepsSRVOrder(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	// the third member is not published, and comes last
+	r := &fakeResolver{records: map[string][]*net.SRV{}}
+	r.set("etcd-client",
+		srvOf(t, cluster.Members[2].URL, 20, 0),
+		srvOf(t, cluster.Members[0].URL, 10, 0),
+	)
+	c, err := NewClientFromSRV("example.com", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{cluster.Members[0].URL, cluster.Members[2].URL, cluster.Members[1].URL}
+	for i := 0; i < 10; i++ {
+		if !c.SyncCluster() {
+			t.Fatal("cannot sync the cluster")
+		}
+		if got := c.GetCluster(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("machines = %v after a sync, want %v", got, want)
+		}
+	}
+	if got := c.cluster.pick(); got != cluster.Members[0].URL {
+		t.Fatalf("picked %s, want the machine with the best priority", got)
+	}
+}
+
+func TestNewClientFromSRVSchemes(t *testing.T) {
+	r := &fakeResolver{records: map[string][]*net.SRV{}}
+	r.set("etcd-client-ssl", &net.SRV{Target: "a.example.com.", Port: 2379})
+	r.set("etcd-client", &net.SRV{Target: "b.example.com.", Port: 4001, Priority: 1})
+
+	c, err := NewClientFromSRV("example.com", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://a.example.com:2379", "http://b.example.com:4001"}
+	if got := c.GetCluster(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("machines = %v, want %v", got, want)
+	}
+
+	if _, err := NewClientFromSRV("example.org", &fakeResolver{}); err == nil {
+		t.Fatal("NewClientFromSRV succeeded without any record")
+	}
+}
+
+func TestSortSRVWeight(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		records := []srvRecord{
+			{url: "light", priority: 1, weight: 0},
+			{url: "heavy", priority: 1, weight: 100},
+			{url: "backup", priority: 2, weight: 100},
+		}
+		sortSRV(records)
+		if records[0].url != "heavy" || records[1].url != "light" || records[2].url != "backup" {
+			t.Fatalf("records = %v", records)
+		}
+	}
+}
+
+func TestSyncClusterResolvesSRV(t *testing.T) {
+	old, replacement := etcdtest.NewCluster(1), etcdtest.NewCluster(2)
+	defer replacement.Close()
+
+	r := &fakeResolver{records: map[string][]*net.SRV{}}
+	r.set("etcd-client", srvOf(t, old.Members[0].URL, 0, 0))
+	c, err := NewClientFromSRV("example.com", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every machine the client knows is replaced
+	old.Close()
+	r.set("etcd-client", srvOf(t, replacement.Members[0].URL, 0, 0))
+	if !c.SyncCluster() {
+		t.Fatal("cannot sync the cluster")
+	}
+	got := sortedMachines(c.GetCluster())
+	if want := sortedMachines(replacement.URLs()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("machines = %v, want %v", got, want)
+	}
+}
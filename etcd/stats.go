@@ -0,0 +1,142 @@
+package etcd
+
+import (
+	"context"
+	"time"
+)
+
+// SelfStats are the statistics a member keeps about itself.
+type SelfStats struct {
+	Name      string    `json:"name"`
+	ID        string    `json:"id"`
+	State     string    `json:"state"`
+	StartTime time.Time `json:"startTime"`
+
+	LeaderInfo LeaderInfo `json:"leaderInfo"`
+
+	RecvAppendRequestCnt uint64  `json:"recvAppendRequestCnt"`
+	RecvPkgRate          float64 `json:"recvPkgRate,omitempty"`
+	RecvBandwidthRate    float64 `json:"recvBandwidthRate,omitempty"`
+	SendAppendRequestCnt uint64  `json:"sendAppendRequestCnt"`
+	SendPkgRate          float64 `json:"sendPkgRate,omitempty"`
+	SendBandwidthRate    float64 `json:"sendBandwidthRate,omitempty"`
+}
+
+// LeaderInfo tells which member leads the cluster, as seen by a member.
+type LeaderInfo struct {
+	// Leader is the ID of the leader.
+	Leader    string    `json:"leader"`
+	Uptime    string    `json:"uptime"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// IsLeader reports whether the member leads the cluster.
+func (s *SelfStats) IsLeader() bool {
+	return s.State == "StateLeader"
+}
+
+// LeaderStats are the statistics the leader keeps about its followers.
+type LeaderStats struct {
+	// Leader is the ID of the leader.
+	Leader string `json:"leader"`
+	// Followers maps the ID of each follower to its statistics.
+	Followers map[string]*FollowerStats `json:"followers"`
+}
+
+// FollowerStats are the statistics the leader keeps about a follower.
+type FollowerStats struct {
+	Latency LatencyStats `json:"latency"`
+	Counts  struct {
+		Fail    uint64 `json:"fail"`
+		Success uint64 `json:"success"`
+	} `json:"counts"`
+}
+
+// LatencyStats are the latencies of the requests the leader sent to a
+// follower, in milliseconds.
+type LatencyStats struct {
+	Current           float64 `json:"current"`
+	Average           float64 `json:"average"`
+	AverageSquare     float64 `json:"averageSquare"`
+	StandardDeviation float64 `json:"standardDeviation"`
+	Minimum           float64 `json:"minimum"`
+	Maximum           float64 `json:"maximum"`
+}
+
+// StoreStats count the operations on the key space of a member.
+type StoreStats struct {
+	GetSuccess              uint64 `json:"getsSuccess"`
+	GetFail                 uint64 `json:"getsFail"`
+	SetSuccess              uint64 `json:"setsSuccess"`
+	SetFail                 uint64 `json:"setsFail"`
+	DeleteSuccess           uint64 `json:"deleteSuccess"`
+	DeleteFail              uint64 `json:"deleteFail"`
+	UpdateSuccess           uint64 `json:"updateSuccess"`
+	UpdateFail              uint64 `json:"updateFail"`
+	CreateSuccess           uint64 `json:"createSuccess"`
+	CreateFail              uint64 `json:"createFail"`
+	CompareAndSwapSuccess   uint64 `json:"compareAndSwapSuccess"`
+	CompareAndSwapFail      uint64 `json:"compareAndSwapFail"`
+	CompareAndDeleteSuccess uint64 `json:"compareAndDeleteSuccess"`
+	CompareAndDeleteFail    uint64 `json:"compareAndDeleteFail"`
+	ExpireCount             uint64 `json:"expireCount"`
+	Watchers                uint64 `json:"watchers"`
+}
+
+// SelfStats returns the statistics of the member the client talks to.
+func (c *Client) SelfStats() (*SelfStats, error) {
+	return c.SelfStatsContext(context.Background())
+}
+
+// SelfStatsContext is like SelfStats but gives up once ctx is done.
+func (c *Client) SelfStatsContext(ctx context.Context) (*SelfStats, error) {
+	stats := new(SelfStats)
+	if err := c.getJSON(ctx, "stats/self", stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// LeaderStats returns the statistics the leader keeps about its
+// followers. Only the leader has them, so the request is sent to it.
+func (c *Client) LeaderStats() (*LeaderStats, error) {
+	return c.LeaderStatsContext(context.Background())
+}
+
+// LeaderStatsContext is like LeaderStats but gives up once ctx is done.
+func (c *Client) LeaderStatsContext(ctx context.Context) (*LeaderStats, error) {
+	if _, err := c.LeaderContext(ctx); err != nil {
+		return nil, err
+	}
+
+	stats := new(LeaderStats)
+	rr := NewRawRequest("GET", "stats/leader", nil, nil)
+	rr.toLeader = true
+	err := c.sendJSON(ctx, rr, stats)
+	if err != nil && isErrorCode(err, ErrCodeUnhandledHTTPStatus) {
+		// the leader changed since the client last learned it
+		if _, err = c.syncLeader(ctx); err != nil {
+			return nil, err
+		}
+		err = c.sendJSON(ctx, rr, stats)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// StoreStats returns the operation counts of the member the client
+// talks to.
+func (c *Client) StoreStats() (*StoreStats, error) {
+	return c.StoreStatsContext(context.Background())
+}
+
+// StoreStatsContext is like StoreStats but gives up once ctx is done.
+func (c *Client) StoreStatsContext(ctx context.Context) (*StoreStats, error) {
+	stats := new(StoreStats)
+	if err := c.getJSON(ctx, "stats/store", stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,86 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestSelfStats(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	leader := cluster.Members[1]
+	cluster.SetLeader(leader)
+
+	for _, m := range cluster.Members {
+		stats, err := NewClient([]string{m.URL}).SelfStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.ID != m.ID || stats.IsLeader() != (m == leader) || stats.LeaderInfo.Leader != leader.ID {
+			t.Fatalf("stats of %s = %+v, want leader %s", m.ID, stats, leader.ID)
+		}
+		if stats.StartTime.IsZero() {
+			t.Fatal("start time is zero")
+		}
+	}
+}
+
+func TestLeaderStats(t *testing.T) {
+	cluster := etcdtest.NewCluster(3)
+	defer cluster.Close()
+
+	cluster.SetLeader(cluster.Members[2])
+	c := NewClient(cluster.URLs())
+	stats, err := c.LeaderStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Leader != cluster.Members[2].ID || len(stats.Followers) != 2 {
+		t.Fatalf("stats = %+v, want 2 followers of %s", stats, cluster.Members[2].ID)
+	}
+	if f := stats.Followers[cluster.Members[0].ID]; f == nil || f.Latency.Average == 0 {
+		t.Fatalf("follower stats = %+v", f)
+	}
+
+	// the client learns a new leader when the old one refuses
+	cluster.SetLeader(cluster.Members[0])
+	if stats, err = c.LeaderStats(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Leader != cluster.Members[0].ID {
+		t.Fatalf("leader = %s, want %s", stats.Leader, cluster.Members[0].ID)
+	}
+}
+
+func TestStoreStats(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	c.Set("foo", "bar", 0)
+	c.Get("foo", false, false)
+	c.Get("missing", false, false)
+	c.Create("foo", "bar", 0)
+	c.CompareAndSwap("foo", "baz", 0, "bar", 0)
+	c.CompareAndDelete("foo", "bar", 0)
+	c.Delete("foo", false)
+
+	stats, err := c.StoreStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StoreStats{
+		SetSuccess:            1,
+		GetSuccess:            1,
+		GetFail:               1,
+		CreateFail:            1,
+		CompareAndSwapSuccess: 1,
+		CompareAndDeleteFail:  1,
+		DeleteSuccess:         1,
+	}
+	if *stats != want {
+		t.Fatalf("stats = %+v, want %+v", *stats, want)
+	}
+}
@@ -0,0 +1,177 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codec turns Go values into node values and back.
+type Codec interface {
+	Marshal(v interface{}) (string, error)
+	Unmarshal(data string, v interface{}) error
+}
+
+// Codecs provided by the package.
+var (
+	// JSONCodec stores values as JSON.
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec stores values with encoding/gob, in base64 since node
+	// values must be text.
+	GobCodec Codec = gobCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (jsonCodec) Unmarshal(data string, v interface{}) error {
+	return json.Unmarshal([]byte(data), v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (gobCodec) Unmarshal(data string, v interface{}) error {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// DecodeError is returned when the value of a node cannot be decoded.
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot decode the value of %s: %v", e.Key, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// errDirValue is the cause of a DecodeError for a directory.
+var errDirValue = errors.New("node is a directory")
+
+// TypedClient stores Go values in nodes, encoded by a Codec, on top of a
+// Client.
+type TypedClient struct {
+	client     *Client
+	valueCodec Codec
+}
+
+// NewTypedClient returns a typed client encoding values with c, or as
+// JSON if c is nil.
+func NewTypedClient(client *Client, c Codec) *TypedClient {
+	if c == nil {
+		c = JSONCodec
+	}
+	return &TypedClient{client: client, valueCodec: c}
+}
+
+// Client returns the client the typed client sends its requests with.
+func (t *TypedClient) Client() *Client {
+	return t.client
+}
+
+// Put sets the value of key to v, encoded.
+func (t *TypedClient) Put(key string, v interface{}, ttl uint64) (*Response, error) {
+	return t.PutContext(context.Background(), key, v, ttl)
+}
+
+// PutContext is like Put but gives up once ctx is done.
+func (t *TypedClient) PutContext(ctx context.Context, key string, v interface{}, ttl uint64) (*Response, error) {
+	value, err := t.valueCodec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.SetContext(ctx, key, value, ttl)
+}
+
+// Create creates key with the value v, encoded, if it does not exist.
+func (t *TypedClient) Create(key string, v interface{}, ttl uint64) (*Response, error) {
+	return t.CreateContext(context.Background(), key, v, ttl)
+}
+
+// CreateContext is like Create but gives up once ctx is done.
+func (t *TypedClient) CreateContext(ctx context.Context, key string, v interface{}, ttl uint64) (*Response, error) {
+	value, err := t.valueCodec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.CreateContext(ctx, key, value, ttl)
+}
+
+// GetInto gets key and decodes its value into v, which must be a
+// pointer. Values that cannot be decoded are reported as a *DecodeError,
+// along with the response.
+func (t *TypedClient) GetInto(key string, v interface{}) (*Response, error) {
+	return t.GetIntoContext(context.Background(), key, v)
+}
+
+// GetIntoContext is like GetInto but gives up once ctx is done.
+func (t *TypedClient) GetIntoContext(ctx context.Context, key string, v interface{}) (*Response, error) {
+	resp, err := t.client.GetContext(ctx, key, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return resp, t.Decode(resp.Node, v)
+}
+
+// CompareAndSwap sets the value of key to v, encoded, if its value is
+// prev, encoded, and its modified index is prevIndex. A nil prev or a
+// zero prevIndex leaves out the matching condition.
+//
+// The values are compared as encoded, so the codec must encode equal
+// values the same way; JSONCodec does, but GobCodec does not for maps.
+func (t *TypedClient) CompareAndSwap(key string, v interface{}, ttl uint64,
+	prev interface{}, prevIndex uint64) (*Response, error) {
+	return t.CompareAndSwapContext(context.Background(), key, v, ttl, prev, prevIndex)
+}
+
+// CompareAndSwapContext is like CompareAndSwap but gives up once ctx is
+// done.
+func (t *TypedClient) CompareAndSwapContext(ctx context.Context, key string, v interface{}, ttl uint64,
+	prev interface{}, prevIndex uint64) (*Response, error) {
+	value, err := t.valueCodec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var prevValue string
+	if prev != nil {
+		if prevValue, err = t.valueCodec.Marshal(prev); err != nil {
+			return nil, err
+		}
+	}
+	return t.client.CompareAndSwapContext(ctx, key, value, ttl, prevValue, prevIndex)
+}
+
+// Decode decodes the value of node into v, such as the nodes returned by
+// a watch.
+func (t *TypedClient) Decode(node *Node, v interface{}) error {
+	if node.Dir {
+		return &DecodeError{Key: node.Key, Err: errDirValue}
+	}
+	if err := t.valueCodec.Unmarshal(node.Value, v); err != nil {
+		return &DecodeError{Key: node.Key, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package etcd
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+type typedConfig struct {
+	Name    string
+	Port    int
+	Enabled bool
+	Tags    []string
+}
+
+// upperCodec stores strings in upper case, to check that any codec can
+// be plugged in.
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v interface{}) (string, error) {
+	return strings.ToUpper(v.(string)), nil
+}
+
+func (upperCodec) Unmarshal(data string, v interface{}) error {
+	*v.(*string) = strings.ToLower(data)
+	return nil
+}
+
+func TestTypedClient(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		tc := NewTypedClient(c, codec)
+		want := typedConfig{Name: "web", Port: 8080, Enabled: true, Tags: []string{"a", "b"}}
+		if _, err := tc.Put("/config", want, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		var got typedConfig
+		resp, err := tc.GetInto("/config", &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%T: got %+v, want %+v", codec, got, want)
+		}
+
+		// swap on the previous value, then on the index
+		next := want
+		next.Port = 9090
+		if _, err := tc.CompareAndSwap("/config", next, 0, want, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tc.CompareAndSwap("/config", want, 0, want, 0); !IsCompareFailed(err) {
+			t.Fatalf("CompareAndSwap = %v, want compare failed", err)
+		}
+		if _, err := tc.CompareAndSwap("/config", want, 0, nil, resp.Node.ModifiedIndex); !IsCompareFailed(err) {
+			t.Fatalf("CompareAndSwap = %v, want compare failed", err)
+		}
+	}
+
+	if _, err := c.Set("/bad", "{", 0); err != nil {
+		t.Fatal(err)
+	}
+	var cfg typedConfig
+	_, err := NewTypedClient(c, nil).GetInto("/bad", &cfg)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Key != "/bad" {
+		t.Fatalf("GetInto = %v, want a decode error", err)
+	}
+	if _, err := NewTypedClient(c, nil).GetInto("/missing", &cfg); !IsKeyNotFound(err) {
+		t.Fatalf("GetInto = %v, want key not found", err)
+	}
+}
+
+func TestTypedClientCustomCodec(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	c := NewClient(cluster.URLs())
+	tc := NewTypedClient(c, upperCodec{})
+	if _, err := tc.Create("/greeting", "hello", 0); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := c.Get("/greeting", false, false); resp.Node.Value != "HELLO" {
+		t.Fatalf("value = %q, want HELLO", resp.Node.Value)
+	}
+	var s string
+	if _, err := tc.GetInto("/greeting", &s); err != nil || s != "hello" {
+		t.Fatalf("GetInto = %q, %v, want hello", s, err)
+	}
+}
@@ -1,6 +1,7 @@
 package etcd
 
 import (
+	"context"
 	"errors"
 )
 
@@ -23,9 +24,24 @@ var (
 // the stop channel.
 func (c *Client) Watch(prefix string, waitIndex uint64, recursive bool,
 	receiver chan *Response, stop chan bool) (*Response, error) {
-	logger.Debugf("watch %s [%s]", prefix, c.cluster.Leader)
+	ctx, cancel := cancelContext(stop)
+	defer cancel()
+
+	resp, err := c.WatchContext(ctx, prefix, waitIndex, recursive, receiver)
+	if err != nil && ctx.Err() != nil {
+		return nil, ErrWatchStoppedByUser
+	}
+	return resp, err
+}
+
+// WatchContext is like Watch, but a long-term watch is stopped by
+// cancelling ctx rather than through a stop channel. It then returns
+// ctx.Err().
+func (c *Client) WatchContext(ctx context.Context, prefix string, waitIndex uint64, recursive bool,
+	receiver chan *Response) (*Response, error) {
+	logger.Debugf("watch %s [%s]", prefix, c.cluster.leader())
 	if receiver == nil {
-		raw, err := c.watchOnce(prefix, waitIndex, recursive, stop)
+		raw, err := c.watchOnce(ctx, prefix, waitIndex, recursive)
 
 		if err != nil {
 			return nil, err
@@ -36,7 +52,7 @@ func (c *Client) Watch(prefix string, waitIndex uint64, recursive bool,
 	defer close(receiver)
 
 	for {
-		raw, err := c.watchOnce(prefix, waitIndex, recursive, stop)
+		raw, err := c.watchOnce(ctx, prefix, waitIndex, recursive)
 
 		if err != nil {
 			return nil, err
@@ -49,20 +65,38 @@ func (c *Client) Watch(prefix string, waitIndex uint64, recursive bool,
 		}
 
 		waitIndex = resp.Node.ModifiedIndex + 1
-		receiver <- resp
+
+		select {
+		case receiver <- resp:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
 func (c *Client) RawWatch(prefix string, waitIndex uint64, recursive bool,
 	receiver chan *RawResponse, stop chan bool) (*RawResponse, error) {
+	ctx, cancel := cancelContext(stop)
+	defer cancel()
 
-	logger.Debugf("rawWatch %s [%s]", prefix, c.cluster.Leader)
+	resp, err := c.RawWatchContext(ctx, prefix, waitIndex, recursive, receiver)
+	if err != nil && ctx.Err() != nil {
+		return nil, ErrWatchStoppedByUser
+	}
+	return resp, err
+}
+
+// RawWatchContext is like RawWatch but is stopped by cancelling ctx.
+func (c *Client) RawWatchContext(ctx context.Context, prefix string, waitIndex uint64, recursive bool,
+	receiver chan *RawResponse) (*RawResponse, error) {
+
+	logger.Debugf("rawWatch %s [%s]", prefix, c.cluster.leader())
 	if receiver == nil {
-		return c.watchOnce(prefix, waitIndex, recursive, stop)
+		return c.watchOnce(ctx, prefix, waitIndex, recursive)
 	}
 
 	for {
-		raw, err := c.watchOnce(prefix, waitIndex, recursive, stop)
+		raw, err := c.watchOnce(ctx, prefix, waitIndex, recursive)
 
 		if err != nil {
 			return nil, err
@@ -75,13 +109,18 @@ func (c *Client) RawWatch(prefix string, waitIndex uint64, recursive bool,
 		}
 
 		waitIndex = resp.Node.ModifiedIndex + 1
-		receiver <- raw
+
+		select {
+		case receiver <- raw:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
 // helper func
 // return when there is change under the given prefix
-func (c *Client) watchOnce(key string, waitIndex uint64, recursive bool, stop chan bool) (*RawResponse, error) {
+func (c *Client) watchOnce(ctx context.Context, key string, waitIndex uint64, recursive bool) (*RawResponse, error) {
 
 	options := Options{
 		"wait": true,
@@ -93,11 +132,30 @@ func (c *Client) watchOnce(key string, waitIndex uint64, recursive bool, stop ch
 		options["recursive"] = true
 	}
 
-	resp, err := c.getCancelable(key, options, stop)
+	return c.get(ctx, key, options)
+}
 
-	if err == ErrRequestCancelled {
-		return nil, ErrWatchStoppedByUser
-	}
+// waitDelete blocks until key is deleted or expires at or after waitIndex.
+// It also returns when the events since waitIndex have been cleared, so
+// callers should check the key again once it returns.
+func (c *Client) waitDelete(ctx context.Context, key string, waitIndex uint64) error {
+	for {
+		raw, err := c.watchOnce(ctx, key, waitIndex, false)
+		if err != nil {
+			return err
+		}
 
-	return resp, err
+		resp, err := raw.Unmarshal()
+		if IsIndexCleared(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if Action(resp.Action).removesNode() {
+			return nil
+		}
+		waitIndex = resp.Node.ModifiedIndex + 1
+	}
 }
@@ -0,0 +1,86 @@
+package etcd
+
+import (
+	"context"
+)
+
+// Action is the kind of operation that made a change reported by a watch.
+type Action string
+
+// Actions reported by etcd for the changes to a key.
+const (
+	ActionSet              Action = "set"
+	ActionCreate           Action = "create"
+	ActionUpdate           Action = "update"
+	ActionDelete           Action = "delete"
+	ActionExpire           Action = "expire"
+	ActionCompareAndSwap   Action = "compareAndSwap"
+	ActionCompareAndDelete Action = "compareAndDelete"
+
+	// ActionResync is the action of the synthetic changes reported by a
+	// Watcher that had to catch up with the current state of its key.
+	ActionResync Action = "resync"
+)
+
+// removesNode reports whether the action removes the node it reports.
+func (a Action) removesNode() bool {
+	return a == ActionDelete || a == ActionExpire || a == ActionCompareAndDelete
+}
+
+// WatchEvent is a change reported by WatchEvents.
+type WatchEvent struct {
+	Action Action
+	// Node is the node after the change. After a removal it only holds the
+	// key and the indexes of the change.
+	Node *Node
+	// PrevNode is the node before the change, if it existed.
+	PrevNode *Node
+	// EtcdIndex is the index of the cluster when the change was reported.
+	EtcdIndex uint64
+}
+
+// WatchEvents reports the changes to key at or after waitIndex, or to any
+// key under it if recursive is set, until ctx is done. To watch for the
+// changes after the call, set waitIndex = 0.
+//
+// The changes are followed by a Watcher, so that the channel also receives
+// ActionResync events. Once watching stops, the event channel is closed
+// and the reason is sent on the error channel, which is then closed too.
+// The error is ctx.Err() when ctx is done.
+func (c *Client) WatchEvents(ctx context.Context, key string, waitIndex uint64,
+	recursive bool) (<-chan WatchEvent, <-chan error) {
+	events := make(chan WatchEvent)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(events)
+
+		w := c.NewWatcher(key, waitIndex, recursive)
+		for {
+			resp, err := w.Next(ctx)
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			select {
+			case events <- newWatchEvent(resp):
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return events, errc
+}
+
+func newWatchEvent(resp *Response) WatchEvent {
+	return WatchEvent{
+		Action:    Action(resp.Action),
+		Node:      resp.Node,
+		PrevNode:  resp.PrevNode,
+		EtcdIndex: resp.EtcdIndex,
+	}
+}
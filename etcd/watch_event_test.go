@@ -0,0 +1,84 @@
+package etcd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestWatchEvents(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, errc := c.WatchEvents(ctx, "/dir", 1, true)
+
+	c.Set("/dir/a", "1", 0)
+	c.Create("/dir/b", "1", 0)
+	c.Update("/dir/a", "2", 0)
+	c.CompareAndSwap("/dir/a", "3", 0, "2", 0)
+	c.CompareAndDelete("/dir/b", "1", 0)
+	c.Set("/dir/c", "1", 1)
+	c.Delete("/dir/a", false)
+
+	tests := []struct {
+		action Action
+		key    string
+		prev   string
+	}{
+		{ActionSet, "/dir/a", ""},
+		{ActionCreate, "/dir/b", ""},
+		{ActionUpdate, "/dir/a", "1"},
+		{ActionCompareAndSwap, "/dir/a", "2"},
+		{ActionCompareAndDelete, "/dir/b", "1"},
+		{ActionSet, "/dir/c", ""},
+		{ActionDelete, "/dir/a", "3"},
+		{ActionExpire, "/dir/c", "1"},
+	}
+
+	for i, tt := range tests {
+		var e WatchEvent
+		select {
+		case e = <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("#%d: no event", i)
+		}
+
+		if e.Action != tt.action || e.Node.Key != tt.key {
+			t.Errorf("#%d: event = %s %s, want %s %s", i, e.Action, e.Node.Key, tt.action, tt.key)
+		}
+		prev := ""
+		if e.PrevNode != nil {
+			prev = e.PrevNode.Value
+		}
+		if prev != tt.prev {
+			t.Errorf("#%d: previous value = %q, want %q", i, prev, tt.prev)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWatchEventsError(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	cluster.Members[0].Inject(etcdtest.Status(http.StatusTeapot), 0)
+	c := NewClient(cluster.URLs())
+
+	events, errc := c.WatchEvents(context.Background(), "/foo", 0, false)
+	if _, ok := <-events; ok {
+		t.Fatal("received an event, want the channel closed")
+	}
+	if err := <-errc; !isErrorCode(err, ErrCodeUnhandledHTTPStatus) {
+		t.Fatalf("err = %v, want error code %d", err, ErrCodeUnhandledHTTPStatus)
+	}
+}
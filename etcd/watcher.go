@@ -0,0 +1,140 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Delays between the attempts of a Watcher to reach the cluster.
+const (
+	watcherMinRetryDelay = 100 * time.Millisecond
+	watcherMaxRetryDelay = 5 * time.Second
+)
+
+// Watcher follows the changes to a key, or to all keys under it, for as
+// long as needed.
+//
+// Unlike Watch, a Watcher does not give up when etcd has already cleared
+// the events it waits for (error 401), which happens when more than a
+// thousand changes were made since. It then gets the current state of the
+// key, returns it as a response with the action ActionResync, and resumes
+// watching from there. It also waits for the cluster to come back when no
+// member can be reached.
+type Watcher struct {
+	client    *Client
+	key       string
+	recursive bool
+
+	// nextMu serializes the calls to Next, while mu only guards waitIndex,
+	// so that WaitIndex does not wait for a pending watch.
+	nextMu    sync.Mutex
+	mu        sync.Mutex
+	waitIndex uint64
+}
+
+// NewWatcher returns a watcher for the changes to key at or after waitIndex,
+// or to any key under it if recursive is set. If waitIndex is 0, it waits
+// for the changes after the first call to Next.
+func (c *Client) NewWatcher(key string, waitIndex uint64, recursive bool) *Watcher {
+	return &Watcher{
+		client:    c,
+		key:       key,
+		recursive: recursive,
+		waitIndex: waitIndex,
+	}
+}
+
+// Next blocks until the next change and returns it, or until ctx is done.
+//
+// A response with the action ActionResync holds the whole current state of
+// the key, as would a recursive Get, in its Node. Node is nil if the key
+// does not exist.
+func (w *Watcher) Next(ctx context.Context) (*Response, error) {
+	w.nextMu.Lock()
+	defer w.nextMu.Unlock()
+
+	delay := watcherMinRetryDelay
+	for {
+		resp, err := w.next(ctx)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !watcherShouldRetry(err) {
+			return nil, err
+		}
+
+		logger.Warningf("watcher: cannot watch %s, retrying in %v: %v", w.key, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if delay *= 2; delay > watcherMaxRetryDelay {
+			delay = watcherMaxRetryDelay
+		}
+	}
+}
+
+// WaitIndex returns the index from which the next change is awaited.
+func (w *Watcher) WaitIndex() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.waitIndex
+}
+
+func (w *Watcher) setWaitIndex(index uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.waitIndex = index
+}
+
+func (w *Watcher) next(ctx context.Context) (*Response, error) {
+	raw, err := w.client.watchOnce(ctx, w.key, w.WaitIndex(), w.recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := raw.Unmarshal()
+	if IsIndexCleared(err) {
+		logger.Debugf("watcher: events of %s cleared, resyncing", w.key)
+		return w.resync(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w.setWaitIndex(resp.Node.ModifiedIndex + 1)
+	return resp, nil
+}
+
+// resync gets the whole current state of the key, sorted, whether or not
+// the watch is recursive.
+func (w *Watcher) resync(ctx context.Context) (*Response, error) {
+	resp, err := w.client.GetContext(ctx, w.key, true, true)
+	if IsKeyNotFound(err) {
+		resp, err = &Response{EtcdIndex: errorIndex(err)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Action = string(ActionResync)
+	w.setWaitIndex(resp.EtcdIndex + 1)
+	return resp, nil
+}
+
+// watcherShouldRetry reports whether err may go away by itself. On top of
+// the errors deemed retryable, such as the clearing of pending watches
+// during a leader change (error 400), this includes the errors not sent by
+// etcd, such as garbled responses from members dying while answering.
+func watcherShouldRetry(err error) bool {
+	var etcdErr *EtcdError
+	return IsRetryable(err) || !errors.As(err, &etcdErr)
+}
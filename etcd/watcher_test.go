@@ -0,0 +1,131 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd/etcdtest"
+)
+
+func TestWatcherResync(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c.Set("/dir/a", "1", 0)
+	w := c.NewWatcher("/dir", 1, true)
+	if resp, err := w.Next(ctx); err != nil || resp.Action != "set" || resp.Node.Key != "/dir/a" {
+		t.Fatalf("Next = %+v, %v, want the set of /dir/a", resp, err)
+	}
+
+	// clear the events the watcher waits for
+	c.Set("/dir/b", "2", 0)
+	for i := 0; i < 1000; i++ {
+		c.Set("/other", "x", 0)
+	}
+
+	resp, err := w.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Action(resp.Action) != ActionResync || len(resp.Node.Nodes) != 2 || resp.Node.Nodes[1].Value != "2" {
+		t.Fatalf("Next = %+v, want a resync with /dir/a and /dir/b", resp)
+	}
+	if w.WaitIndex() != resp.EtcdIndex+1 {
+		t.Fatalf("WaitIndex = %d, want %d", w.WaitIndex(), resp.EtcdIndex+1)
+	}
+
+	c.Set("/dir/c", "3", 0)
+	if resp, err = w.Next(ctx); err != nil || resp.Node.Key != "/dir/c" {
+		t.Fatalf("Next = %+v, %v, want the set of /dir/c", resp, err)
+	}
+
+	// a key that no longer exists resyncs to nothing
+	w = c.NewWatcher("/gone", 1, false)
+	if resp, err = w.Next(ctx); err != nil || Action(resp.Action) != ActionResync || resp.Node != nil {
+		t.Fatalf("Next = %+v, %v, want an empty resync", resp, err)
+	}
+}
+
+func TestWatcherFailover(t *testing.T) {
+	cluster := etcdtest.NewCluster(2)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := NewClient(cluster.URLs())
+	w := c.NewWatcher("/foo", cluster.Index()+1, false)
+
+	for _, m := range cluster.Members {
+		m.Kill()
+	}
+	time.AfterFunc(300*time.Millisecond, func() {
+		cluster.Members[1].Heal()
+		NewClient(cluster.URLs()[1:]).Set("/foo", "bar", 0)
+	})
+
+	resp, err := w.Next(ctx)
+	if err != nil || resp.Node.Value != "bar" {
+		t.Fatalf("Next = %+v, %v, want the set of /foo", resp, err)
+	}
+}
+
+func TestWatcherCleared(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := NewClient(cluster.URLs())
+	w := c.NewWatcher("/foo", cluster.Index()+1, false)
+
+	// pending watches are dropped while a leader is elected
+	cluster.Members[0].Inject(etcdtest.WatcherCleared(), 2)
+	time.AfterFunc(300*time.Millisecond, func() { c.Set("/foo", "bar", 0) })
+
+	resp, err := w.Next(ctx)
+	if err != nil || resp.Node.Value != "bar" {
+		t.Fatalf("Next = %+v, %v, want the set of /foo", resp, err)
+	}
+}
+
+func TestWatcherWaitIndex(t *testing.T) {
+	cluster := etcdtest.NewCluster(1)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewClient(cluster.URLs())
+	index := cluster.Index() + 1
+	w := c.NewWatcher("/foo", index, false)
+	go w.Next(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	// reading the progress does not wait for the pending watch
+	got := make(chan uint64)
+	go func() { got <- w.WaitIndex() }()
+	select {
+	case i := <-got:
+		if i != index {
+			t.Fatalf("WaitIndex = %d, want %d", i, index)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitIndex blocked by a pending Next")
+	}
+}
+
+func TestWatcherCancel(t *testing.T) {
+	c := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.NewWatcher("/foo", 0, false).Next(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Next = %v, want %v", err, context.DeadlineExceeded)
+	}
+}